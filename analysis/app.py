@@ -8,24 +8,19 @@ st.title("📈 Stock Agent Analysis Dashboard")
 # CSVの読み込み
 try:
     # Goが出力するCSVのパスを指定（親ディレクトリにある想定）
-    df = pd.read_csv("../results.csv", names=[
-        "Date", "Ticker", "CompanyName", "Action", "Confidence", 
-        "Reasoning", "Financials", "Technicals", "PromptID"
-    ], header=0)
+    # 列はヘッダーの列名で読む (列が増えても位置がずれないように)
+    df = pd.read_csv("../results.csv", dtype={"Ticker": str, "DisclosedTime": str})
 except FileNotFoundError:
     st.error("results.csv not found. Run the Go agent first.")
     st.stop()
 
-# データ加工: Technicalsから数値を抽出（正規表現などでパース）
-# 例: "Volatility: 4.06%" -> 4.06
-import re
-
-def extract_metric(text, pattern):
-    match = re.search(pattern, str(text))
-    return float(match.group(1)) if match else None
-
-df['Volatility'] = df['Technicals'].apply(lambda x: extract_metric(x, r'Volatility:\s*([\d\.]+)%'))
-df['Liquidity'] = df['Technicals'].apply(lambda x: extract_metric(x, r'Avg Trading Value:\s*([\d]+)'))
+# 株価トレンドの数値は Go 側が列 (AvgVolatilityPct, AvgValueJPY など) として出力する
+# 列が増える前に記録された行は空欄になる (Technicals の文字列には残っている)
+for col in ['AvgVolatilityPct', 'AvgValueJPY']:
+    if col not in df.columns:
+        df[col] = None
+df['Volatility'] = pd.to_numeric(df['AvgVolatilityPct'], errors='coerce')
+df['Liquidity'] = pd.to_numeric(df['AvgValueJPY'], errors='coerce')
 
 # サイドバーフィルタ
 st.sidebar.header("Filter")
@@ -1,40 +1,44 @@
 package main
 
 import (
-	"encoding/csv"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
-	"os"
 	"time"
 
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/cmdutil"
 	"github.com/oooooorriiiii/stock-agent-jpx/internal/config"
 	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
 )
 
 func main() {
-	cfg := config.Load()
-	jq := jquants.NewClient(cfg.JQuantsRefreshToken)
+	var cacheFlags cmdutil.CacheFlags
+	cacheFlags.Register(flag.CommandLine)
+	var sourceFlags cmdutil.SourceFlags
+	sourceFlags.Register(flag.CommandLine)
+	flag.Parse()
 
-	file, err := os.Open("results.csv")
+	cfg := config.Load()
+	md, closeData, err := cmdutil.OpenMarketData(cfg, sourceFlags, cacheFlags)
 	if err != nil {
-		log.Fatalf("Failed to open results.csv: %v", err)
+		log.Fatalf("Failed to open market data: %v", err)
 	}
-	defer file.Close()
+	defer closeData()
 
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
+	rows, err := cmdutil.ReadResults("results.csv")
 	if err != nil {
-		log.Fatal(err)
+		log.Fatalf("Failed to read results.csv: %v", err)
 	}
 
 	log.Println("--- Analyzing Missed Opportunities (IGNORE -> Skyrocket) ---")
 
-	for i, record := range records {
-		if i == 0 { continue }
-		dateStr := record[0]
-		ticker := record[1]
-		action := record[2]
-		reason := record[4] // Reasoning
+	for _, row := range rows {
+		dateStr := row["Date"]
+		ticker := row["Ticker"]
+		action := row["Action"]
+		reason := row["Reasoning"]
 
 		// IGNORE と判断したものだけをチェック
 		if action != "IGNORE" {
@@ -45,8 +49,14 @@ func main() {
 		fromDate := analyzeDate.Format("2006-01-02")
 		toDate := analyzeDate.AddDate(0, 0, 7).Format("2006-01-02")
 
-		quotes, err := jq.GetDailyQuotes(ticker, fromDate, toDate)
-		if err != nil { continue }
+		quotes, err := md.GetDailyQuotesWithMode(context.Background(), ticker, fromDate, toDate, jquants.PriceRaw)
+		if errors.Is(err, jquants.ErrUnauthorized) {
+			log.Fatalf("J-Quants authentication failed: %v", err)
+		}
+		if err != nil {
+			log.Printf("API Error %s: %v", ticker, err)
+			continue
+		}
 		if len(quotes) < 2 { continue }
 
 		// prevDay := quotes[0]
@@ -55,9 +65,9 @@ func main() {
 		// 翌日大きく上昇したか？ (例: +3%以上)
 		openPrice := targetDay.Open
 		highPrice := targetDay.High
-		
+
 		if openPrice == 0 { continue }
-		
+
 		maxReturn := (highPrice - openPrice) / openPrice * 100
 
 		// AIは見送ったが、実は3%以上取れた銘柄を表示
@@ -68,4 +78,4 @@ func main() {
 			fmt.Println("   ------------------------------------------------")
 		}
 	}
-}
\ No newline at end of file
+}
@@ -2,54 +2,94 @@ package main
 
 import (
 	"context"
-	"encoding/csv"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"maps"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/oooooorriiiii/stock-agent-jpx/internal/agent"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/calendar"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/cmdutil"
 	"github.com/oooooorriiiii/stock-agent-jpx/internal/config"
 	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/marketdata"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/universe"
 )
 
 const MaxRetries = 5
 
 func main() {
+	var cacheFlags cmdutil.CacheFlags
+	cacheFlags.Register(flag.CommandLine)
+	var sourceFlags cmdutil.SourceFlags
+	sourceFlags.Register(flag.CommandLine)
+	mode := flag.String("mode", "analyze", "実行モード: analyze (開示済み決算の分析) / watchlist (翌営業日の決算発表予定の監視リスト作成)")
+	marketFlag := flag.String("market", "", "対象の市場区分 (カンマ区切り: prime / standard / growth または市場区分コード)。空なら全市場")
+	excludeSectorsFlag := flag.String("exclude-sectors", "", "除外する業種 (カンマ区切り: banks / financials / realestate、33業種コード、17業種コード)")
+	indicatorsFlag := flag.String("indicators", "all", "get_price_trend に付けるテクニカル指標 (カンマ区切り: "+strings.Join(agent.AllIndicators, " / ")+"、all ですべて、none で付けない)")
+	flag.Parse()
+
+	markets, err := universe.ParseMarkets(*marketFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sector33, sector17, err := universe.ParseSectors(*excludeSectorsFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	indicators, err := agent.ParseIndicators(*indicatorsFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	filter := universe.Filter{Markets: markets, ExcludeSector33: sector33, ExcludeSector17: sector17}
+	log.Printf("Universe: %s", filter)
+
 	cfg := config.Load()
 
 	// 検証期間
 	startDateStr := "2025-07-01"
 	endDateStr := "2025-07-22"
 
-	// CSV準備（CompanyNameを追加）
-	file, _ := os.OpenFile("results.csv", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	defer file.Close()
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	stat, _ := file.Stat()
-	if stat.Size() == 0 {
-		// ヘッダーに CompanyName を追加
-		writer.Write([]string{
-			"Date", "Ticker", "CompanyName", "Action", "Confidence", "Reasoning",
-			"Financials", "Technicals", "PromptID",
-		})
+	md, closeData, err := cmdutil.OpenMarketData(cfg, sourceFlags, cacheFlags, jquants.WithUserAgent("stock-agent-jpx"))
+	if err != nil {
+		log.Fatalf("Failed to open market data: %v", err)
 	}
+	defer closeData()
 
-	jq := jquants.NewClient(cfg.JQuantsRefreshToken)
-	ctx := context.Background()
+	// Ctrl-C でリクエスト中の J-Quants / Gemini 呼び出しもキャンセルする
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
-	log.Println("Loading listed company info...")
-	nameMap, err := jq.GetListedInfoMap()
+	// 取引カレンダーは監視リストの作成・日付のループ・ツールで共有する
+	cal, err := calendar.Load(ctx, md)
 	if err != nil {
-		log.Printf("Warning: Failed to load company names: %v", err)
-		nameMap = make(map[string]string)
+		log.Fatalf("Failed to load trading calendar: %v", err)
 	}
-	log.Printf("Loaded %d companies.", len(nameMap))
 
-	analyzer, err := agent.NewStockAnalyzer(ctx, cfg.GoogleAPIKey, jq)
+	if *mode == "watchlist" {
+		if err := runWatchlist(ctx, md, cal, filter, "watchlist.csv"); err != nil {
+			log.Fatalf("Failed to build watchlist: %v", err)
+		}
+		return
+	}
+	if *mode != "analyze" {
+		log.Fatalf("Unknown mode: %s", *mode)
+	}
+
+	// CSV準備 (列構成が古いファイルは列名で対応付けて書き直される)
+	results, err := cmdutil.AppendResults("results.csv")
+	if err != nil {
+		log.Fatalf("Failed to open results.csv: %v", err)
+	}
+	defer results.Close()
+
+	analyzer, err := agent.NewStockAnalyzer(ctx, cfg.GoogleAPIKey, md, agent.WithIndicators(indicators), agent.WithCalendar(cal))
 	if err != nil {
 		log.Fatalf("Failed to init analyzer: %v", err)
 	}
@@ -57,11 +97,21 @@ func main() {
 	start, _ := time.Parse("2006-01-02", startDateStr)
 	end, _ := time.Parse("2006-01-02", endDateStr)
 
+	// 週末・祝日にも開示はあるので (cmd/sync と同じく) 決算は暦日ごとに取得する
+	// 株価を見るツールは base_date が休日なら直前の立会日までを使う
 	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		if ctx.Err() != nil {
+			log.Println("Interrupted. Stopping batch analysis.")
+			break
+		}
 		targetDate := d.Format("2006-01-02")
 		log.Printf("\n========== Processing Date: %s ==========", targetDate)
 
-		statements, err := jq.GetStatements(targetDate)
+		statements, err := md.GetStatementsContext(ctx, targetDate)
+		if errors.Is(err, jquants.ErrUnauthorized) {
+			// トークンの問題は日付を変えても解決しないので中断する
+			log.Fatalf("J-Quants authentication failed: %v", err)
+		}
 		if err != nil {
 			log.Printf("Failed to fetch data: %v", err)
 			continue
@@ -71,14 +121,41 @@ func main() {
 			continue
 		}
 
+		// 開示日時点の上場情報 (社名・市場区分・業種) で対象銘柄を絞り込む
+		// 上場情報は立会日ごとに更新されるので、休日の開示は直前の立会日時点の情報を使う
+		infoDate := targetDate
+		if !cal.IsTradingDay(d) {
+			if prev, err := cal.SessionsBack(d, 0); err == nil {
+				infoDate = prev.Format("2006-01-02")
+			}
+		}
+		infos, err := listedInfoByCode(ctx, md, infoDate)
+		if errors.Is(err, jquants.ErrUnauthorized) {
+			log.Fatalf("J-Quants authentication failed: %v", err)
+		}
+		if err != nil {
+			if !filter.IsZero() {
+				log.Printf("Failed to load listed info: %v. Skipping.", err)
+				continue
+			}
+			log.Printf("Warning: Failed to load company names: %v", err)
+		}
+
 		log.Printf("Found %d statements. Starting analysis...\n", len(statements))
 
 		for i, s := range statements {
+			if ctx.Err() != nil {
+				break
+			}
 			if s.OperatingProfit == "" {
 				continue
 			}
 
-			companyName := nameMap[s.LocalCode]
+			info, ok := infos[s.LocalCode]
+			if !filter.IsZero() && (!ok || !filter.Match(info)) {
+				continue
+			}
+			companyName := info.CompanyName
 			if companyName == "" {
 				companyName = "Unknown"
 			}
@@ -94,7 +171,7 @@ func main() {
 			for attempt := 1; attempt <= MaxRetries; attempt++ {
 				eval, err = analyzer.Analyze(ctx, s)
 
-				if err == nil {
+				if err == nil || ctx.Err() != nil {
 					break
 				}
 
@@ -122,6 +199,21 @@ func main() {
 			} else {
 				fmt.Printf("   📈 Technicals: (Not checked)\n")
 			}
+			if rs := eval.ToolOutputs["get_relative_strength"]; rs != "" {
+				fmt.Printf("   🧭 Relative Strength:\n      %s\n", strings.ReplaceAll(rs, "\n", "\n      "))
+			}
+			if es := eval.ToolOutputs["get_earnings_surprise"]; es != "" {
+				fmt.Printf("   🎯 Earnings Surprise:\n      %s\n", strings.ReplaceAll(es, "\n", "\n      "))
+			}
+			if sp := eval.ToolOutputs["get_sector_peers"]; sp != "" {
+				fmt.Printf("   🏭 Sector Peers:\n      %s\n", strings.ReplaceAll(sp, "\n", "\n      "))
+			}
+			if vl := eval.ToolOutputs["get_valuation"]; vl != "" {
+				fmt.Printf("   💴 Valuation:\n      %s\n", strings.ReplaceAll(vl, "\n", "\n      "))
+			}
+			if am := eval.ToolOutputs["get_morning_session"]; am != "" {
+				fmt.Printf("   🌅 Morning Session (disclosed %s):\n      %s\n", s.DisclosedTime, strings.ReplaceAll(am, "\n", "\n      "))
+			}
 
 			icon := "💤"
 			if eval.Action == "BUY" {
@@ -134,19 +226,51 @@ func main() {
 			// 改行を " | " に置換して1行にする
 			cleanTech := strings.ReplaceAll(eval.TechnicalSummary, "\n", " | ")
 
-			writer.Write([]string{
-				targetDate,
-				eval.Ticker,
-				companyName, // 追加
-				eval.Action,
-				fmt.Sprintf("%.2f", eval.Confidence),
-				eval.Reasoning,
-				eval.FinancialSummary,
-				cleanTech, // 整形済みデータ
-				eval.PromptID,
-			})
-			writer.Flush()
+			row := cmdutil.ResultRow{
+				"Date":          targetDate,
+				"Ticker":        eval.Ticker,
+				"CompanyName":   companyName,
+				"Action":        eval.Action,
+				"Confidence":    fmt.Sprintf("%.2f", eval.Confidence),
+				"Reasoning":     eval.Reasoning,
+				"Financials":    eval.FinancialSummary,
+				"Technicals":    cleanTech, // 整形済みデータ
+				"PromptID":      eval.PromptID,
+				"DisclosedTime": s.DisclosedTime, // 昼の開示をバックテストで後場寄りエントリーするのに使う
+			}
+			maps.Copy(row, trendColumns(eval.Technicals))
+			if err := results.Write(row); err != nil {
+				log.Fatalf("Failed to write results.csv: %v", err)
+			}
 		}
 	}
 	log.Println("\n========== Batch Analysis Completed ==========")
 }
+
+// 株価トレンドの数値を cmdutil.TrendColumns の列にする (トレンドが取れなかった場合は空欄)
+func trendColumns(r *agent.PriceTrendResult) cmdutil.ResultRow {
+	if r == nil || r.Trend == "" {
+		return cmdutil.ResultRow{}
+	}
+	return cmdutil.ResultRow{
+		"Trend":            r.Trend,
+		"ChangePct":        fmt.Sprintf("%.2f", r.ChangePct),
+		"AvgValueJPY":      fmt.Sprintf("%.0f", r.AvgValueJPY),
+		"AvgVolatilityPct": fmt.Sprintf("%.2f", r.AvgVolatilityPct),
+		"LatestClose":      strconv.FormatFloat(r.LatestClose, 'f', -1, 64),
+		"SessionsUsed":     strconv.Itoa(r.SessionsUsed),
+	}
+}
+
+// date 時点の上場銘柄情報 (Code -> ListedInfo)。date が空なら最新
+func listedInfoByCode(ctx context.Context, md marketdata.MarketData, date string) (map[string]jquants.ListedInfo, error) {
+	infos, err := md.GetListedInfo(ctx, "", date)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]jquants.ListedInfo, len(infos))
+	for _, info := range infos {
+		m[info.Code] = info
+	}
+	return m, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/agent"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/calendar"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/cmdutil"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/marketdata"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/universe"
+)
+
+// 決算発表予定から監視リストを作る
+// 発表前日までの値動きを一覧にしておき、決算が出た銘柄からすぐ分析へ移れるようにする
+// (分析時の get_price_trend は base_date までの株価を取り直すので、ここで取得した株価は使い回さない)
+func runWatchlist(ctx context.Context, md marketdata.MarketData, cal *calendar.Calendar, filter universe.Filter, path string) error {
+	ann, err := marketdata.Extension[marketdata.AnnouncementData](md)
+	if err != nil {
+		return err
+	}
+	announcements, err := ann.GetAnnouncements(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("Found %d upcoming announcements.", len(announcements))
+
+	// 発表予定の銘柄を最新の上場情報で絞り込む
+	if !filter.IsZero() {
+		infos, err := listedInfoByCode(ctx, md, "")
+		if err != nil {
+			return err
+		}
+		announcements = slices.DeleteFunc(announcements, func(a jquants.Announcement) bool {
+			info, ok := infos[a.Code]
+			return !ok || !filter.Match(info)
+		})
+		log.Printf("%d announcements in universe (%s).", len(announcements), filter)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	writer.Write(append([]string{
+		"AnnouncementDate", "Ticker", "CompanyName", "FiscalQuarter", "Sector", "Section", "BaseDate", "PriceContext",
+	}, cmdutil.TrendColumns...))
+
+	trendTool := &agent.PriceTrendTool{Data: md, Calendar: cal}
+
+	for i, a := range announcements {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// 発表日の前営業日までの値動きを「発表前の状態」として記録する
+		baseDate := ""
+		priceContext := "(Announcement date undecided)"
+		var trend *agent.PriceTrendResult
+		if annDate, err := time.Parse("2006-01-02", a.Date); err == nil {
+			if prev, err := cal.PrevTradingDay(annDate); err == nil {
+				baseDate = prev.Format("2006-01-02")
+				r, err := trendTool.Trend(ctx, a.Code, baseDate)
+				if err != nil {
+					log.Printf("❌ Failed to fetch price context for %s: %v", a.Code, err)
+					priceContext = fmt.Sprintf("(Error: %v)", err)
+				} else {
+					priceContext, trend = r.Analysis, &r
+				}
+			}
+		}
+
+		fmt.Printf("📅 [%d/%d] %s %s (%s) %s\n", i+1, len(announcements), a.Date, a.Code, a.CompanyName, a.FiscalQuarter)
+
+		record := []string{
+			a.Date,
+			a.Code,
+			a.CompanyName,
+			a.FiscalQuarter,
+			a.SectorName,
+			a.Section,
+			baseDate,
+			strings.ReplaceAll(priceContext, "\n", " | "),
+		}
+		columns := trendColumns(trend)
+		for _, col := range cmdutil.TrendColumns {
+			record = append(record, columns[col])
+		}
+		writer.Write(record)
+		writer.Flush()
+	}
+
+	log.Printf("Watchlist written to %s", path)
+	return nil
+}
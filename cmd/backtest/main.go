@@ -1,54 +1,85 @@
 package main
 
 import (
-	"encoding/csv"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
-	"os"
 	"time"
 
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/benchmark"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/calendar"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/cmdutil"
 	"github.com/oooooorriiiii/stock-agent-jpx/internal/config"
 	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/marketdata"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/pointintime"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/regime"
 )
 
 func main() {
+	var cacheFlags cmdutil.CacheFlags
+	cacheFlags.Register(flag.CommandLine)
+	var sourceFlags cmdutil.SourceFlags
+	sourceFlags.Register(flag.CommandLine)
+	// 前日終値と翌日始値の間に分割があってもギャップが崩れないよう、デフォルトは調整済み価格で比較する
+	priceModeFlag := flag.String("price-mode", string(jquants.PriceAdjusted), "価格系列 (adjusted / raw)")
+	entryFlag := flag.String("entry", entryNextOpen, "エントリー方法: next-open (翌営業日の寄り) / afternoon-open (前場中・昼休みの開示は当日の後場寄り、それ以外は翌営業日の寄り)")
+	flag.Parse()
+
+	priceMode, err := jquants.ParsePriceMode(*priceModeFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *entryFlag != entryNextOpen && *entryFlag != entryAfternoonOpen {
+		log.Fatalf("invalid entry %q (want %q or %q)", *entryFlag, entryNextOpen, entryAfternoonOpen)
+	}
+
 	cfg := config.Load()
-	jq := jquants.NewClient(cfg.JQuantsRefreshToken)
+	md, closeData, err := cmdutil.OpenMarketData(cfg, sourceFlags, cacheFlags)
+	if err != nil {
+		log.Fatalf("Failed to open market data: %v", err)
+	}
+	defer closeData()
 
-	file, err := os.Open("results.csv")
+	cal, err := calendar.Load(context.Background(), md)
 	if err != nil {
-		log.Fatalf("Failed to open results.csv: %v", err)
+		log.Fatalf("Failed to load trading calendar: %v", err)
 	}
-	defer file.Close()
 
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
+	rows, err := cmdutil.ReadResults("results.csv")
 	if err != nil {
-		log.Fatal(err)
+		log.Fatalf("Failed to read results.csv: %v", err)
 	}
 
 	// 設定: ギャップ上限（これ以上高く寄り付いたら買わない）
 	const MaxGapThreshold = 2.5 // +2.5%
 
-	log.Printf("--- Starting Backtest (Filter: Gap < %.1f%%) ---", MaxGapThreshold)
+	log.Printf("--- Starting Backtest (Filter: Gap < %.1f%%, Prices: %s, Entry: %s) ---", MaxGapThreshold, priceMode, *entryFlag)
 	
 	winCount := 0
 	tradeCount := 0
 	skippedGapCount := 0
+	afternoonCount := 0
 	
+	// ベンチマーク (エントリー日の TOPIX 騰落率) の集計用
+	bench := &benchmark.Benchmark{Data: md, Calendar: cal}
+	asOfEntry := &pointintime.Guard{Data: md}
+	var totalDayReturn, totalTopixChange float64
+	benchCount := 0
+
+	// 海外投資家フローの地合い別の勝敗
+	regimeTrades := make(map[string]int)
+	regimeWins := make(map[string]int)
+
 	// 重複チェック用マップ (Key: "Date-Ticker")
 	processed := make(map[string]bool)
 
-	for i, record := range records {
-		if i == 0 { continue }
-
-		// 0:Date, 1:Ticker, 2:CompanyName, 3:Action, ...
-		if len(record) < 4 { continue } // 安全策
-		
-		dateStr := record[0]
-		ticker := record[1]
-		// companyName := record[2] // 必要なら表示に使用
-		action := record[3]
+	for _, row := range rows {
+		dateStr := row["Date"]
+		ticker := row["Ticker"]
+		action := row["Action"]
 
 		if action != "BUY" { continue }
 
@@ -60,37 +91,32 @@ func main() {
 		processed[key] = true
 
 		analyzeDate, _ := time.Parse("2006-01-02", dateStr)
-		fromDate := analyzeDate.Format("2006-01-02")
-		toDate := analyzeDate.AddDate(0, 0, 7).Format("2006-01-02")
 
-		quotes, err := jq.GetDailyQuotes(ticker, fromDate, toDate)
-		if err != nil {
-			log.Printf("API Error %s: %v", ticker, err)
-			continue
+		// 前場中・昼休みの開示は当日の後場寄りでエントリーする (後場・前場別のデータがなければ翌営業日の寄り)
+		var entry *entryQuote
+		var err error
+		if *entryFlag == entryAfternoonOpen && calendar.MiddayDisclosure(row["DisclosedTime"]) && cal.IsTradingDay(analyzeDate) {
+			entry, err = afternoonOpenEntry(context.Background(), md, ticker, analyzeDate, priceMode)
+			if err == nil && entry == nil {
+				log.Printf("No afternoon session data for %s on %s. Falling back to next open.", ticker, dateStr)
+			}
 		}
-		if len(quotes) < 2 {
-			continue
+		if err == nil && entry == nil {
+			entry, err = nextOpenEntry(context.Background(), md, cal, ticker, analyzeDate, priceMode)
 		}
-
-		// データ検索: PrevClose(分析日) と EntryDay(翌営業日) を特定
-		var prevDay, targetDay jquants.DailyQuote
-		found := false
-		
-		// quote[0]が分析日(dateStr)と一致するか確認
-		for j := 0; j < len(quotes)-1; j++ {
-			if quotes[j].Date == dateStr {
-				prevDay = quotes[j]
-				targetDay = quotes[j+1]
-				found = true
-				break
-			}
+		if errors.Is(err, jquants.ErrUnauthorized) {
+			log.Fatalf("J-Quants authentication failed: %v", err)
 		}
-		
-		if !found || prevDay.Close <= 0 || targetDay.Open <= 0 { continue }
+		if err != nil {
+			log.Printf("Entry Error %s: %v", ticker, err)
+			continue
+		}
+		if entry == nil { continue }
+		entryDate := entry.Date
 
-		// Gap計算
-		prevClose := prevDay.Close
-		entryPrice := targetDay.Open
+		// Gap計算 (エントリー直前の値段との比較)
+		prevClose := entry.RefPrice
+		entryPrice := entry.Entry
 		gapPercent := (entryPrice - prevClose) / prevClose * 100
 
 		// === フィルタリング: 高すぎる寄り付きは避ける ===
@@ -102,7 +128,7 @@ func main() {
 
 		// トレード判定 (TP: +1%)
 		targetPrice := entryPrice * 1.01 
-		maxPrice := targetDay.High
+		maxPrice := entry.High
 		isWin := maxPrice >= targetPrice
 		
 		resultStr := "LOSE ❌"
@@ -111,11 +137,49 @@ func main() {
 			winCount++
 		}
 		tradeCount++
+		if entry.Afternoon {
+			afternoonCount++
+		}
 
 		maxReturn := (maxPrice - entryPrice) / entryPrice * 100
 
-		fmt.Printf("[%s] Gap:%+6.2f%% | Entry:%5.0f -> High:%5.0f (Max:+%.2f%%) | Result: %s\n", 
-			ticker, gapPercent, entryPrice, maxPrice, maxReturn, resultStr)
+		// ベンチマーク: エントリー日の寄り→引けを TOPIX と比較する
+		dayReturn := (entry.Close - entryPrice) / entryPrice * 100
+		benchStr := "TOPIX:   N/A"
+		if topixChange, err := bench.TopixDayChange(context.Background(), entryDate); err == nil {
+			benchStr = fmt.Sprintf("TOPIX:%+6.2f%%", topixChange)
+			totalDayReturn += dayReturn
+			totalTopixChange += topixChange
+			benchCount++
+		} else if !errors.Is(err, marketdata.ErrUnsupported) && !errors.Is(err, benchmark.ErrInsufficientData) {
+			log.Printf("Benchmark Error %s: %v", entryDate.Format("2006-01-02"), err)
+		}
+
+		// エントリー時点 (寄り付き) で公表済みの海外投資家フローから地合いを判定する
+		// エントリー日の大引け後に公表される週は含めないよう、分析と同じく pointintime.Guard を通す
+		flowStr := "N/A"
+		entryClock := calendar.MorningOpen
+		if entry.Afternoon {
+			entryClock = calendar.AfternoonOpen
+		}
+		entryAt, _ := pointintime.At(entryDate.Format("2006-01-02"), entryClock)
+		flowCtx := pointintime.WithNow(context.Background(), entryAt)
+		if flow, err := regime.ForeignFlowRegime(flowCtx, asOfEntry, jquants.SectionTSEPrime, entryDate, regime.DefaultWeeks); err == nil {
+			flowStr = flow.Regime
+			regimeTrades[flow.Regime]++
+			if isWin {
+				regimeWins[flow.Regime]++
+			}
+		} else if !errors.Is(err, marketdata.ErrUnsupported) {
+			log.Printf("Regime Error %s: %v", entryDate.Format("2006-01-02"), err)
+		}
+
+		entryLabel := "Entry"
+		if entry.Afternoon {
+			entryLabel = "PM Entry"
+		}
+		fmt.Printf("[%s] Gap:%+6.2f%% | %s:%5.0f -> High:%5.0f (Max:+%.2f%%) | Day:%+6.2f%% %s | Flow: %-7s | Result: %s\n", 
+			ticker, gapPercent, entryLabel, entryPrice, maxPrice, maxReturn, dayReturn, benchStr, flowStr, resultStr)
 	}
 
 	if tradeCount > 0 {
@@ -125,7 +189,99 @@ func main() {
 		fmt.Printf("Wins:         %d\n", winCount)
 		fmt.Printf("Win Rate:     %.1f%%\n", winRate)
 		fmt.Printf("Skipped Gaps: %d\n", skippedGapCount)
+		if *entryFlag == entryAfternoonOpen {
+			fmt.Printf("PM Entries:   %d\n", afternoonCount)
+		}
+		if benchCount > 0 {
+			avgDay := totalDayReturn / float64(benchCount)
+			avgTopix := totalTopixChange / float64(benchCount)
+			fmt.Printf("Avg Day Return: %+.2f%% (TOPIX: %+.2f%%, Excess: %+.2fpt)\n", avgDay, avgTopix, avgDay-avgTopix)
+		}
+		for _, r := range []string{regime.Inflow, regime.Mixed, regime.Outflow} {
+			if n := regimeTrades[r]; n > 0 {
+				fmt.Printf("Flow %-7s:  %d trades, Win Rate %.1f%%\n", r, n, float64(regimeWins[r])/float64(n)*100)
+			}
+		}
 	} else {
 		fmt.Println("No valid trades found.")
 	}
-}
\ No newline at end of file
+}
+const (
+	entryNextOpen      = "next-open"
+	entryAfternoonOpen = "afternoon-open"
+)
+
+// 1トレード分のエントリー価格と、エントリー後 (大引けまで) の値動き
+type entryQuote struct {
+	Date      time.Time
+	Afternoon bool    // 後場寄りでエントリーしたか
+	RefPrice  float64 // ギャップの基準 (前日終値 or 前場引け)
+	Entry     float64
+	High      float64 // エントリー後の高値
+	Close     float64
+}
+
+// 分析日の翌営業日の寄り付きでエントリーする
+// PrevClose(分析日以前の直近営業日) と EntryDay(分析日の翌営業日) をカレンダーで特定するので
+// 分析日が休日 (休日開示) でも正しく前後の営業日を取れる
+// 価格が揃わない場合は nil
+func nextOpenEntry(ctx context.Context, md marketdata.MarketData, cal *calendar.Calendar, ticker string, analyzeDate time.Time, mode jquants.PriceMode) (*entryQuote, error) {
+	prevDate, err := cal.SessionsBack(analyzeDate, 0)
+	if err != nil {
+		return nil, err
+	}
+	entryDate, err := cal.NextTradingDay(analyzeDate)
+	if err != nil {
+		return nil, err
+	}
+
+	quotes, err := md.GetDailyQuotesWithMode(ctx, ticker, prevDate.Format("2006-01-02"), entryDate.Format("2006-01-02"), mode)
+	if err != nil {
+		return nil, err
+	}
+
+	var prevDay, targetDay jquants.DailyQuote
+	for _, q := range quotes {
+		switch q.Date {
+		case prevDate.Format("2006-01-02"):
+			prevDay = q
+		case entryDate.Format("2006-01-02"):
+			targetDay = q
+		}
+	}
+	if prevDay.Close <= 0 || targetDay.Open <= 0 {
+		return nil, nil
+	}
+	return &entryQuote{
+		Date:     entryDate,
+		RefPrice: prevDay.Close,
+		Entry:    targetDay.Open,
+		High:     targetDay.High,
+		Close:    targetDay.Close,
+	}, nil
+}
+
+// 開示日 (立会日) の後場寄りでエントリーする
+// 前場引けをギャップの基準にし、後場の高値・大引けで判定する
+// 前場・後場別のデータがない場合は nil
+func afternoonOpenEntry(ctx context.Context, md marketdata.MarketData, ticker string, date time.Time, mode jquants.PriceMode) (*entryQuote, error) {
+	d := date.Format("2006-01-02")
+	quotes, err := md.GetDailyQuotesWithMode(ctx, ticker, d, d, mode)
+	if err != nil {
+		return nil, err
+	}
+	for _, q := range quotes {
+		if q.Date != d || q.MorningClose <= 0 || q.AfternoonOpen <= 0 {
+			continue
+		}
+		return &entryQuote{
+			Date:      date,
+			Afternoon: true,
+			RefPrice:  q.MorningClose,
+			Entry:     q.AfternoonOpen,
+			High:      q.AfternoonHigh,
+			Close:     q.AfternoonClose,
+		}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquantstest"
+)
+
+// オフラインのデモ用に J-Quants の偽サーバーを起動する
+// 他のコマンドは JQUANTS_BASE_URL=http://localhost:8080 と
+// JQUANTS_REFRESH_TOKEN=test-refresh-token を設定すればこのサーバーに接続する
+func main() {
+	addr := flag.String("addr", "localhost:8080", "待ち受けるアドレス")
+	fixturesDir := flag.String("fixtures", "", "fixture JSON のディレクトリ (空なら同梱のサンプルデータ)")
+	pageSize := flag.Int("page-size", jquantstest.DefaultPageSize, "1ページあたりの件数 (0 ならページングしない)")
+	flag.Parse()
+
+	fx := jquantstest.DefaultFixtures()
+	if *fixturesDir != "" {
+		var err error
+		fx, err = jquantstest.LoadFixtures(os.DirFS(*fixturesDir))
+		if err != nil {
+			log.Fatalf("Failed to load fixtures: %v", err)
+		}
+	}
+
+	h := jquantstest.NewHandler(fx)
+	h.PageSize = *pageSize
+
+	log.Printf("Fake J-Quants server listening on http://%s (refresh token: %s)", *addr, jquantstest.RefreshToken)
+	log.Fatal(http.ListenAndServe(*addr, h))
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/calendar"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/cmdutil"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/config"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/store"
+)
+
+// J-Quants のデータを日付単位でローカルデータストアにダウンロードする
+// 同期済みの日付は飛ばすので、定期的に実行すれば差分だけを取得する
+func main() {
+	storePath := flag.String("store", "", "保存先の SQLite ファイル (デフォルト: $JQUANTS_STORE_PATH または "+config.DefaultJQuantsStorePath+")")
+	fromFlag := flag.String("from", "", "取得開始日 (YYYY-MM-DD)。省略時はデータセットごとに最後に同期した日の翌日から")
+	toFlag := flag.String("to", "", "取得終了日 (YYYY-MM-DD)。省略時は今日")
+	datasetsFlag := flag.String("datasets", strings.Join(allDatasets, ","), "取得するデータ (カンマ区切り)")
+	force := flag.Bool("force", false, "同期済みの日付も取得し直す")
+	flag.Parse()
+
+	datasets := strings.Split(*datasetsFlag, ",")
+	for _, d := range datasets {
+		if !slices.Contains(allDatasets, d) {
+			log.Fatalf("Unknown dataset %q (want %s)", d, strings.Join(allDatasets, ", "))
+		}
+	}
+
+	if err := run(*storePath, *fromFlag, *toFlag, datasets, *force); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// エラーは main で終了させる (Close などの defer を走らせるため、ここでは log.Fatal しない)
+func run(storePath, fromFlag, toFlag string, datasets []string, force bool) error {
+	cfg := config.Load()
+	if storePath == "" {
+		storePath = cfg.JQuantsStorePath
+	}
+
+	// ストア自体が永続化されるので、レスポンスキャッシュは使わない
+	jq, err := cmdutil.NewJQuantsClient(cfg, cmdutil.CacheFlags{NoCache: true}, jquants.WithUserAgent("stock-agent-jpx-sync"))
+	if err != nil {
+		return fmt.Errorf("failed to init J-Quants client: %w", err)
+	}
+	st, err := store.Open(storePath)
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+	defer st.Close()
+
+	// 中断しても日付単位でコミット済みなので、次回は続きから取得できる
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	today := time.Now().In(calendar.JST).Format("2006-01-02")
+	to := toFlag
+	if to == "" {
+		to = today
+	}
+
+	// 立会日の判定に使うので、カレンダーは常に全期間を取り直す
+	days, err := jq.GetTradingCalendar(ctx, "", "")
+	if errors.Is(err, jquants.ErrUnauthorized) {
+		return fmt.Errorf("J-Quants authentication failed: %w", err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch trading calendar: %w", err)
+	}
+	if err := st.SaveTradingCalendar(ctx, days); err != nil {
+		return fmt.Errorf("failed to save trading calendar: %w", err)
+	}
+	log.Printf("Synced trading calendar (%d days).", len(days))
+
+	s := &syncer{jq: jq, st: st, today: today, force: force}
+	for _, dataset := range datasets {
+		from := fromFlag
+		if from == "" {
+			last, err := st.LastSynced(ctx, dataset)
+			if err != nil {
+				return fmt.Errorf("failed to read sync state: %w", err)
+			}
+			if last == "" {
+				return fmt.Errorf("%s has never been synced. Specify -from for the first sync", dataset)
+			}
+			t, _ := time.Parse("2006-01-02", last)
+			from = t.AddDate(0, 0, 1).Format("2006-01-02")
+		}
+
+		log.Printf("========== Syncing %s: %s - %s ==========", dataset, from, to)
+		if err := s.syncRange(ctx, dataset, days, from, to); err != nil {
+			if ctx.Err() != nil {
+				log.Println("Interrupted. Run again to resume.")
+				return nil
+			}
+			if errors.Is(err, jquants.ErrUnauthorized) {
+				return fmt.Errorf("J-Quants authentication failed: %w", err)
+			}
+			return fmt.Errorf("failed to sync %s: %w", dataset, err)
+		}
+	}
+	log.Println("========== Sync Completed ==========")
+	return nil
+}
+
+var allDatasets = []string{store.DatasetListedInfo, store.DatasetStatements, store.DatasetDailyQuotes}
+
+type syncer struct {
+	jq    *jquants.Client
+	st    *store.Store
+	today string
+	force bool
+}
+
+// dataset の from〜to を1日ずつ取得して保存する
+// 決算は休日に開示されることもあるので全日、株価と上場銘柄一覧は立会日だけ
+func (s *syncer) syncRange(ctx context.Context, dataset string, days []jquants.TradingCalendarDay, from, to string) error {
+	for _, day := range days {
+		d := day.Date
+		if d < from || d > to {
+			continue
+		}
+		if dataset != store.DatasetStatements && !day.IsTradingDay() {
+			continue
+		}
+		if !s.force {
+			synced, err := s.st.Synced(ctx, dataset, d)
+			if err != nil {
+				return err
+			}
+			if synced {
+				continue
+			}
+		}
+
+		n, err := s.syncDate(ctx, dataset, d)
+		if err != nil {
+			return fmt.Errorf("%s: %w", d, err)
+		}
+		log.Printf("%s %s: %d records", d, dataset, n)
+	}
+	return nil
+}
+
+func (s *syncer) syncDate(ctx context.Context, dataset string, date string) (int, error) {
+	switch dataset {
+	case store.DatasetDailyQuotes:
+		quotes, err := s.jq.GetDailyQuotesByDate(ctx, date)
+		if err != nil {
+			return 0, err
+		}
+		// 当日分は引け後に配信されるので、まだ空なら同期済みにせず次回取り直す
+		return len(quotes), s.st.SaveDailyQuotes(ctx, date, quotes, len(quotes) > 0)
+	case store.DatasetStatements:
+		statements, err := s.jq.GetStatementsContext(ctx, date)
+		if err != nil {
+			return 0, err
+		}
+		// 当日分は開示が続くので同期済みにしない
+		return len(statements), s.st.SaveStatements(ctx, date, statements, date < s.today)
+	case store.DatasetListedInfo:
+		infos, err := s.jq.GetListedInfo(ctx, "", date)
+		if err != nil {
+			return 0, err
+		}
+		return len(infos), s.st.SaveListedInfo(ctx, date, infos)
+	}
+	return 0, nil
+}
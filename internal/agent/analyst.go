@@ -3,6 +3,7 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -15,7 +16,11 @@ import (
 	"google.golang.org/adk/tool/functiontool"
 	"google.golang.org/genai"
 
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/benchmark"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/calendar"
 	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/marketdata"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/pointintime"
 )
 
 // サービスの構造体
@@ -31,27 +36,65 @@ type Evaluation struct {
 	Confidence float64 `json:"confidence"`
 	Reasoning  string  `json:"reasoning"`
 
-	PromptID         string `json:"-"` // JSONからは読み込まないが、CSV出力用に構造体に持たせる
-	FinancialSummary string `json:"-"` // 入力した財務データの要約
-	TechnicalSummary string `json:"-"` // ツールが返したテクニカル分析結果
+	PromptID         string            `json:"-"` // JSONからは読み込まないが、CSV出力用に構造体に持たせる
+	FinancialSummary string            `json:"-"` // 入力した財務データの要約
+	TechnicalSummary string            `json:"-"` // ツールが返したテクニカル分析結果
+	Technicals       *PriceTrendResult `json:"-"` // 同じ結果の数値 (get_price_trend が呼ばれなかった場合は nil)
+
+	ToolOutputs map[string]string `json:"-"` // 呼ばれた全ツールの結果 (ツール名 -> 結果)
+}
+
+// NewStockAnalyzer のオプション
+type Option func(*options)
+
+type options struct {
+	indicators []string
+	calendar   *calendar.Calendar
+}
+
+// get_price_trend に付けるテクニカル指標を選ぶ (デフォルトは AllIndicators)
+func WithIndicators(names []string) Option {
+	return func(o *options) { o.indicators = names }
+}
+
+// 読み込み済みの取引カレンダーを使う (指定しなければ md から読み込む)
+// カレンダーは先の日付まで公表済みなので、分析時点で絞り込まずに共有してよい
+func WithCalendar(cal *calendar.Calendar) Option {
+	return func(o *options) { o.calendar = cal }
 }
 
 // 初期化関数 (ここでModelやToolのセットアップを1回だけ行う)
-func NewStockAnalyzer(ctx context.Context, apiKey string, jq *jquants.Client) (*StockAnalyzer, error) {
+func NewStockAnalyzer(ctx context.Context, apiKey string, md marketdata.MarketData, opts ...Option) (*StockAnalyzer, error) {
+	o := options{indicators: AllIndicators}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	// 1. Model初期化
+	if apiKey == "" {
+		return nil, errors.New("GOOGLE_API_KEY must be set to run the analysis")
+	}
 	clientConfig := &genai.ClientConfig{APIKey: apiKey}
 	model, err := gemini.NewModel(ctx, "gemini-2.5-pro", clientConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create model: %w", err)
 	}
 
-	// 2. Tool初期化 (jqクライアントを注入)
-	trendToolInstance := &PriceTrendTool{Client: jq}
+	// 2. Tool初期化 (市場データの取得元と取引カレンダーを注入)
+	// ツールは Analyze で ctx に設定した分析時点 (開示日時) より後のデータを見られないようにする
+	md = &pointintime.Guard{Data: md}
+	cal := o.calendar
+	if cal == nil {
+		if cal, err = calendar.Load(ctx, md); err != nil {
+			return nil, err
+		}
+	}
+	trendToolInstance := &PriceTrendTool{Data: md, Calendar: cal, Indicators: o.indicators}
 
 	trendTool, err := functiontool.New(
 		functiontool.Config{
 			Name:        "get_price_trend",
-			Description: "Get recent stock price trend to filter out downtrends.",
+			Description: "Get recent stock price trend to filter out downtrends, with technical indicators (moving averages, RSI, MACD, ATR, Bollinger Bands, volume z-score, 52-week range).",
 		},
 		trendToolInstance.Execute, // メソッドをハンドラとして渡す
 	)
@@ -59,6 +102,104 @@ func NewStockAnalyzer(ctx context.Context, apiKey string, jq *jquants.Client) (*
 		return nil, fmt.Errorf("failed to create tool: %w", err)
 	}
 
+	rsToolInstance := &RelativeStrengthTool{
+		Benchmark: &benchmark.Benchmark{Data: md, Calendar: cal},
+	}
+	rsTool, err := functiontool.New(
+		functiontool.Config{
+			Name:        "get_relative_strength",
+			Description: "Compare the stock's recent price change against TOPIX and its sector index.",
+		},
+		rsToolInstance.Execute,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tool: %w", err)
+	}
+
+	sdToolInstance := &SupplyDemandTool{Data: md, Calendar: cal}
+	sdTool, err := functiontool.New(
+		functiontool.Config{
+			Name:        "get_supply_demand",
+			Description: "Get margin trading balance trend (long/short, margin ratio) and sector short-selling ratio.",
+		},
+		sdToolInstance.Execute,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tool: %w", err)
+	}
+
+	dbToolInstance := &DividendBalanceSheetTool{Data: md}
+	dbTool, err := functiontool.New(
+		functiontool.Config{
+			Name:        "get_dividend_and_balance_sheet",
+			Description: "Get recent dividend announcements (hikes/cuts, revisions) and balance-sheet quality (cash, debt, equity ratio).",
+		},
+		dbToolInstance.Execute,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tool: %w", err)
+	}
+
+	mrToolInstance := &MarketRegimeTool{Data: md}
+	mrTool, err := functiontool.New(
+		functiontool.Config{
+			Name:        "get_market_regime",
+			Description: "Get the market regime based on recent foreign investor net buying/selling (weekly, TSE Prime).",
+		},
+		mrToolInstance.Execute,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tool: %w", err)
+	}
+
+	amToolInstance := &MorningSessionTool{Data: md, Calendar: cal}
+	amTool, err := functiontool.New(
+		functiontool.Config{
+			Name:        "get_morning_session",
+			Description: "Get the morning session (9:00-11:30) prices of the disclosure date compared with the previous close. Use for disclosures made during market hours.",
+		},
+		amToolInstance.Execute,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tool: %w", err)
+	}
+
+	esToolInstance := &EarningsSurpriseTool{Data: md}
+	esTool, err := functiontool.New(
+		functiontool.Config{
+			Name:        "get_earnings_surprise",
+			Description: "Compare the latest earnings report with prior disclosures: YoY growth, progress against the full-year forecast, and upward/downward forecast revisions.",
+		},
+		esToolInstance.Execute,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tool: %w", err)
+	}
+
+	spToolInstance := &SectorPeerTool{Data: md, Calendar: cal}
+	spTool, err := functiontool.New(
+		functiontool.Config{
+			Name:        "get_sector_peers",
+			Description: "Rank the stock's growth (YoY), valuation (forward PER, PBR) and 20-day return against listed peers in the same sector.",
+		},
+		spToolInstance.Execute,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tool: %w", err)
+	}
+
+	vlToolInstance := &ValuationTool{Data: md}
+	vlTool, err := functiontool.New(
+		functiontool.Config{
+			Name:        "get_valuation",
+			Description: "Get valuation metrics as of the date: forward/trailing PER, PEG, PBR, dividend yield, market cap and EV multiple.",
+		},
+		vlToolInstance.Execute,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tool: %w", err)
+	}
+
 	// 3. Agent初期化
 	sysPrompt := `
 You are a highly skilled Alpha Seeker AI.
@@ -66,7 +207,15 @@ Your goal is to construct a winning portfolio by balancing "Earnings Power" and
 
 # Input Data
 1. **Financials**: Focus on "Next Year Forecast" growth.
-2. **Technicals (Tool)**: You MUST call the tool "get_price_trend" to get Trend, Liquidity, and Volatility.
+2. **Technicals (Tool)**: You MUST call the tool "get_price_trend" to get Trend, Liquidity, and Volatility (plus technical indicators such as RSI, MACD and Bollinger Bands when provided).
+3. **Relative Strength (Tool)**: Call "get_relative_strength" to see whether the stock is outperforming TOPIX and its sector.
+4. **Supply/Demand (Tool)**: Call "get_supply_demand" to check margin balances and short selling.
+5. **Dividends & Balance Sheet (Tool)**: Call "get_dividend_and_balance_sheet" for payout changes and financial health.
+6. **Market Regime (Tool)**: Call "get_market_regime" to see whether foreign investors are buying or selling Japanese stocks.
+7. **Morning Session (Tool)**: If the disclosure is marked as MIDDAY, call "get_morning_session" to see how the stock traded before the news.
+8. **Earnings Surprise (Tool)**: You MUST call "get_earnings_surprise" to compare the report with last year and with the previous forecast.
+9. **Sector Peers (Tool)**: Call "get_sector_peers" to see how growth, valuation and recent performance rank against same-sector peers.
+10. **Valuation (Tool)**: You MUST call "get_valuation" to see what the price already pays for (PER, PBR, dividend yield, market cap).
 
 # The "Trader's Constitution" (Must Follow):
 1. **Liquidity is Life**: 
@@ -77,7 +226,37 @@ Your goal is to construct a winning portfolio by balancing "Earnings Power" and
    - We need >1.5% daily volatility to make a profit.
    - **Rule**: If volatility is < 1.0%, IGNORE.
 3. **Don't Fight the Trend**:
-   - Buying a DOWNTREND stock requires a "Positive Surprise" catalyst.
+   - Buying a DOWNTREND stock requires a "Positive Surprise" catalyst (see rule 9).
+   - A stock falling only because the whole market/sector fell is less concerning than one underperforming its benchmark.
+4. **Watch the Overhang**:
+   - A high and rising margin ratio (heavy long margin positions) means future selling pressure.
+   - A low margin ratio or rising short interest can fuel a short squeeze after a positive surprise.
+5. **Shareholder Returns & Safety**:
+   - A dividend hike (or upward revision) alongside earnings growth is a strong confidence signal from management.
+   - Heavy net debt or a thin equity ratio makes an earnings miss far more dangerous.
+
+6. **Respect the Tide**:
+   - In an OUTFLOW regime, even good earnings often fail to lift prices. Demand a higher bar for BUY.
+   - In an INFLOW regime, solid earnings are more likely to be rewarded.
+7. **Midday Disclosures**:
+   - For MIDDAY disclosures we enter at the afternoon open of the same day, not the next morning.
+   - If the morning session already rallied strongly, part of the surprise may be priced in (or leaked). Demand more upside.
+8. **Overbought / Oversold**:
+   - RSI above 70, a close above the upper Bollinger Band (%B > 1) or a large premium over the 25-day MA means the good news may already be priced in. Demand a bigger surprise.
+   - RSI below 30 or a close near the 52-week low can set up a rebound on a positive surprise, but a DOWNTREND with MACD below its signal is still a falling knife.
+   - A volume z-score above +2 shows unusual attention. Treat it as confirmation, not as a reason to buy by itself.
+9. **Define the Surprise with Numbers**:
+   - A "Positive Surprise" must be backed by "get_earnings_surprise": an UPWARD forecast revision, progress clearly ahead of the typical pace, strong YoY growth, or a full-year actual beating the last forecast.
+   - A DOWNWARD revision, or progress far behind the typical pace, is a negative surprise even if YoY growth looks fine.
+   - Unchanged guidance with on-pace progress is NOT a surprise.
+10. **Judge Relative to Peers**:
+   - Growth that ranks near the top of its sector while the valuation (forward PER/PBR) ranks near the cheap end is the best setup.
+   - Growth in line with peers at a premium valuation is already priced in. Demand a clear surprise.
+   - If the whole sector rallied over 20 days, the stock's own rally says little. Prefer leaders whose fundamentals also lead.
+11. **Don't Overpay for Growth**:
+   - Weigh growth against price: a PEG well above 2 or a forward PER far above the sector median needs exceptional growth to justify it.
+   - Cheap stocks (low PER/PBR, high dividend yield) without growth or a catalyst are value traps, not bargains.
+   - A small market cap with a big surprise moves more, but check liquidity (rule 1) first.
 
 # Decision Process:
 - Do not use rigid thresholds, but weigh the Risk/Reward.
@@ -90,7 +269,7 @@ JSON: {"ticker": string, "action": "BUY"|"IGNORE", "confidence": float, "reasoni
 		Name:        "ai_trader",
 		Model:       model,
 		Instruction: sysPrompt,
-		Tools:       []tool.Tool{trendTool},
+		Tools:       []tool.Tool{trendTool, rsTool, sdTool, dbTool, mrTool, amTool, esTool, spTool, vlTool},
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create agent: %w", err)
@@ -116,7 +295,16 @@ JSON: {"ticker": string, "action": "BUY"|"IGNORE", "confidence": float, "reasoni
 
 // 分析実行関数
 // 1回の呼び出しごとに新しいセッションを作成・破棄して、前の銘柄の会話履歴を引きずらないようにします
+// ツールが参照できるデータは開示日時まで (ctx に pointintime.WithNow で分析時点を設定済みならその時点まで)
 func (s *StockAnalyzer) Analyze(ctx context.Context, data jquants.FinancialStatement) (*Evaluation, error) {
+	if _, ok := pointintime.Now(ctx); !ok {
+		now, err := pointintime.At(data.DisclosedDate, data.DisclosedTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid disclosure time: %w", err)
+		}
+		ctx = pointintime.WithNow(ctx, now)
+	}
+
 	// セッションIDの生成 (銘柄ごとにユニークにするか、都度生成)
 	// ここではシンプルに毎回新規セッションを作成
 	sess, err := s.sessionService.Create(ctx, &session.CreateRequest{
@@ -130,16 +318,22 @@ func (s *StockAnalyzer) Analyze(ctx context.Context, data jquants.FinancialState
 	defer s.sessionService.Delete(ctx, &session.DeleteRequest{SessionID: sess.Session.ID()})
 
 	// 2. プロンプト作成 & 財務サマリの記録
-	finSummary := fmt.Sprintf(
-		"OpProfit: %s (Fcst: %s) | NextYear: %s",
-		data.OperatingProfit, data.ForecastOperatingProfit, data.NextYearForecastOperatingProfit,
-	)
+	parsed, err := data.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("statement parse error: %w", err)
+	}
+	finSummary := summarizeFinancials(parsed)
 
 	// プロンプト作成
+	// 前場中・昼休みの開示は前場と比べて評価させる (後場寄りでエントリーする想定)
+	disclosed := data.DisclosedTime
+	if calendar.MiddayDisclosure(data.DisclosedTime) {
+		disclosed += " (MIDDAY)"
+	}
 	userPrompt := fmt.Sprintf(`
-Analyze Ticker: %s (Date: %s)
+Analyze Ticker: %s (Date: %s, Disclosed at: %s JST)
 %s
-`, data.LocalCode, data.DisclosedDate, finSummary)
+`, data.LocalCode, data.DisclosedDate, disclosed, finSummary)
 
 	// 実行
 	events := s.runner.Run(
@@ -152,7 +346,8 @@ Analyze Ticker: %s (Date: %s)
 
 	// 4. 結果の取得とパース（ツール出力のキャプチャ機能を追加）
 	var lastText string
-	var toolOutput string // ツールの実行結果を保持
+	toolOutputs := make(map[string]string) // ツール名 -> 実行結果
+	var technicals *PriceTrendResult
 
 	for event, err := range events {
 		if err != nil {
@@ -168,11 +363,19 @@ Analyze Ticker: %s (Date: %s)
 
 				if part.FunctionResponse != nil {
 					// 構造体のフィールドに直接アクセス
+					var output string
 					if val, ok := part.FunctionResponse.Response["analysis"]; ok {
-						toolOutput = fmt.Sprintf("%v", val)
+						output = fmt.Sprintf("%v", val)
 					} else {
-						// resultキーがない場合は全体を保存
-						toolOutput = fmt.Sprintf("%v", part.FunctionResponse.Response)
+						// analysisキーがない場合は全体を保存
+						output = fmt.Sprintf("%v", part.FunctionResponse.Response)
+					}
+					toolOutputs[part.FunctionResponse.Name] = output
+					if part.FunctionResponse.Name == "get_price_trend" {
+						technicals, err = decodeResponse[PriceTrendResult](part.FunctionResponse.Response)
+						if err != nil {
+							return nil, fmt.Errorf("decode get_price_trend response: %w", err)
+						}
 					}
 				}
 			}
@@ -193,13 +396,28 @@ Analyze Ticker: %s (Date: %s)
 
 	// 付帯情報の格納
 	eval.Ticker = data.LocalCode
-	eval.PromptID = "v5_liquidity_filter"
+	eval.PromptID = "v15_valuation"
 	eval.FinancialSummary = finSummary
-	eval.TechnicalSummary = toolOutput // キャプチャしたツール結果を格納
+	eval.TechnicalSummary = toolOutputs["get_price_trend"] // キャプチャしたツール結果を格納
+	eval.Technicals = technicals
+	eval.ToolOutputs = toolOutputs
 
 	return eval, nil
 }
 
+// ツールの結果 (JSON を map にしたもの) を元の構造体に戻す
+func decodeResponse[T any](resp map[string]any) (*T, error) {
+	b, err := json.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+	var v T
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
 func parseJSONResponse(text string) (*Evaluation, error) {
 	// マークダウンの ```json ... ``` を除去する簡易処理
 	start := strings.Index(text, "{")
@@ -215,3 +433,52 @@ func parseJSONResponse(text string) (*Evaluation, error) {
 	}
 	return &eval, nil
 }
+
+// 財務データを1行のサマリにする (プロンプトとCSVの両方で使う)
+// 金額は百万円単位、値がない項目は N/A
+func summarizeFinancials(p *jquants.ParsedStatement) string {
+	parts := []string{
+		fmt.Sprintf("[%s %s]", p.TypeOfDocument, p.TypeOfCurrentPeriod),
+		fmt.Sprintf("Sales: %s", fmtMillions(p.NetSales)),
+		fmt.Sprintf("OpProfit: %s (Fcst: %s)", fmtMillions(p.OperatingProfit), fmtMillions(p.ForecastOperatingProfit)),
+		fmt.Sprintf("OrdProfit: %s", fmtMillions(p.OrdinaryProfit)),
+		fmt.Sprintf("Profit: %s", fmtMillions(p.Profit)),
+		fmt.Sprintf("EPS: %s (Fcst: %s)", fmtNumber(p.EarningsPerShare), fmtNumber(p.ForecastEarningsPerShare)),
+	}
+
+	// 来期予想は今期予想に対する伸び率も添える
+	nextYear := fmt.Sprintf("NextYear OpProfit: %s", fmtMillions(p.NextYearForecastOperatingProfit))
+	if g, ok := growthPct(p.NextYearForecastOperatingProfit, p.ForecastOperatingProfit); ok {
+		nextYear += fmt.Sprintf(" (%+.1f%% vs Fcst)", g)
+	}
+	parts = append(parts, nextYear)
+
+	// N/A が未開示なのか読めなかったのかを区別できるようにする
+	if len(p.Warnings) > 0 {
+		parts = append(parts, fmt.Sprintf("Unparsed: %s", strings.Join(p.Warnings, "; ")))
+	}
+
+	return strings.Join(parts, " | ")
+}
+
+func fmtMillions(v *float64) string {
+	if v == nil {
+		return "N/A"
+	}
+	return fmt.Sprintf("%.0fM", *v/1e6)
+}
+
+func fmtNumber(v *float64) string {
+	if v == nil {
+		return "N/A"
+	}
+	return fmt.Sprintf("%.2f", *v)
+}
+
+// base から v への変化率 (%)。base が 0 以下の場合は意味がないので false
+func growthPct(v, base *float64) (float64, bool) {
+	if v == nil || base == nil || *base <= 0 {
+		return 0, false
+	}
+	return (*v - *base) / *base * 100, true
+}
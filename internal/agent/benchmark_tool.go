@@ -0,0 +1,45 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/benchmark"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/marketdata"
+	"google.golang.org/adk/tool"
+)
+
+// -------------------------------------------------------
+// 相対力 (TOPIX・業種別指数との比較) ツール
+// -------------------------------------------------------
+type RelativeStrengthArgs struct {
+	Ticker   string `json:"ticker" jsonschema:"The stock ticker symbol (e.g., '72030')."`
+	BaseDate string `json:"base_date" jsonschema:"The reference date for analysis (YYYY-MM-DD)."`
+}
+
+type RelativeStrengthResult struct {
+	Analysis string `json:"analysis"`
+}
+
+type RelativeStrengthTool struct {
+	Benchmark *benchmark.Benchmark
+}
+
+func (t *RelativeStrengthTool) Execute(ctx tool.Context, args RelativeStrengthArgs) (RelativeStrengthResult, error) {
+	baseDate, err := time.Parse("2006-01-02", args.BaseDate)
+	if err != nil {
+		return RelativeStrengthResult{}, fmt.Errorf("invalid date format")
+	}
+
+	rs, err := t.Benchmark.RelativeStrength(ctx, args.Ticker, baseDate, trendSessions)
+	if errors.Is(err, jquants.ErrNotFound) || errors.Is(err, jquants.ErrBadRequest) || errors.Is(err, marketdata.ErrUnsupported) ||
+		errors.Is(err, benchmark.ErrInsufficientData) {
+		return RelativeStrengthResult{Analysis: fmt.Sprintf("Benchmark data unavailable for %s as of %s.", args.Ticker, args.BaseDate)}, nil
+	}
+	if err != nil {
+		return RelativeStrengthResult{}, err
+	}
+	return RelativeStrengthResult{Analysis: rs.String()}, nil
+}
@@ -0,0 +1,232 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/marketdata"
+	"google.golang.org/adk/tool"
+)
+
+// -------------------------------------------------------
+// 配当・財務体質 (fs_details) ツール
+// -------------------------------------------------------
+type DividendBalanceSheetArgs struct {
+	Ticker   string `json:"ticker" jsonschema:"The stock ticker symbol (e.g., '72030')."`
+	BaseDate string `json:"base_date" jsonschema:"The reference date for analysis (YYYY-MM-DD)."`
+}
+
+type DividendBalanceSheetResult struct {
+	Analysis string `json:"analysis"`
+}
+
+type DividendBalanceSheetTool struct {
+	Data marketdata.MarketData // 配当・財務諸表の詳細には marketdata.DividendData も必要
+}
+
+const (
+	// 直近の発表を前年同期と比較できるよう、配当発表は約16ヶ月分遡る
+	dividendLookbackDays = 490
+	// 表示する配当発表の件数
+	dividendMaxRecords = 4
+)
+
+// 会計基準ごとの勘定科目名 (先に見つかったものを使う)
+var (
+	cashKeys           = []string{"Cash and deposits", "Cash and cash equivalents (IFRS)", "Cash and cash equivalents"}
+	totalAssetKeys     = []string{"Total assets", "Total assets (IFRS)"}
+	equityKeys         = []string{"Net assets", "Equity attributable to owners of parent (IFRS)", "Total equity (IFRS)"}
+	debtKeysByStandard = [][]string{
+		// 日本基準
+		{"Short-term loans payable", "Current portion of long-term loans payable", "Commercial papers", "Current portion of bonds", "Bonds payable", "Long-term loans payable"},
+		// IFRS
+		{"Bonds and borrowings - CL (IFRS)", "Bonds and borrowings - NCL (IFRS)"},
+	}
+)
+
+func (t *DividendBalanceSheetTool) Execute(ctx tool.Context, args DividendBalanceSheetArgs) (DividendBalanceSheetResult, error) {
+	baseDate, err := time.Parse("2006-01-02", args.BaseDate)
+	if err != nil {
+		return DividendBalanceSheetResult{}, fmt.Errorf("invalid date format")
+	}
+
+	md, err := marketdata.Extension[marketdata.DividendData](t.Data)
+	if err != nil {
+		return DividendBalanceSheetResult{Analysis: "Dividend / Balance Sheet: N/A (not available from this data source)"}, nil
+	}
+
+	dividend, err := t.dividendSummary(ctx, md, args.Ticker, baseDate)
+	if err != nil {
+		return DividendBalanceSheetResult{}, err
+	}
+	balance, err := t.balanceSheetSummary(ctx, md, args.Ticker, baseDate)
+	if err != nil {
+		return DividendBalanceSheetResult{}, err
+	}
+	return DividendBalanceSheetResult{Analysis: dividend + "\n" + balance}, nil
+}
+
+// 直近の配当発表と、その修正幅・前年同期比
+func (t *DividendBalanceSheetTool) dividendSummary(ctx context.Context, md marketdata.DividendData, ticker string, baseDate time.Time) (string, error) {
+	fromDate := baseDate.AddDate(0, 0, -dividendLookbackDays).Format("2006-01-02")
+	records, err := md.GetDividends(ctx, ticker, fromDate, baseDate.Format("2006-01-02"))
+	if errors.Is(err, jquants.ErrNotFound) || errors.Is(err, jquants.ErrBadRequest) {
+		records, err = nil, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch dividends: %w", err)
+	}
+
+	var valid []jquants.Dividend
+	for _, d := range records {
+		if d.StatusCode != jquants.DividendStatusDeleted {
+			valid = append(valid, d)
+		}
+	}
+	if len(valid) == 0 {
+		return "Dividends: No announcements in the last 16 months.", nil
+	}
+	sort.SliceStable(valid, func(i, j int) bool {
+		return valid[i].AnnouncementDate+valid[i].AnnouncementTime < valid[j].AnnouncementDate+valid[j].AnnouncementTime
+	})
+
+	lines := []string{"Dividends (latest first):"}
+	for i := len(valid) - 1; i >= 0 && i >= len(valid)-dividendMaxRecords; i-- {
+		d := valid[i]
+		line := fmt.Sprintf("- %s: %s %s %s DPS %s", d.AnnouncementDate, d.InterimFinalTerm,
+			interimFinalLabel(d.InterimFinalCode), forecastResultLabel(d.ForecastResultCode), fmtDividendRate(d.GrossDividendRate))
+		if d.CommemorativeSpecialCode != "" && d.CommemorativeSpecialCode != "0" {
+			line += " (incl. commemorative/special)"
+		}
+		if prev, ok := previousDividend(valid[:i], d); ok {
+			line += " " + prev
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// d より前の発表から比較対象を探す
+// 同じ基準日の発表があれば修正 (増配・減配の修正)、なければ前年同期と比べる
+func previousDividend(earlier []jquants.Dividend, d jquants.Dividend) (string, bool) {
+	cur, _ := jquants.ParseNumber(d.GrossDividendRate)
+	if cur == nil {
+		return "", false
+	}
+
+	lastYearTerm := ""
+	if y, err := strconv.Atoi(strings.SplitN(d.InterimFinalTerm, "-", 2)[0]); err == nil && len(d.InterimFinalTerm) > 4 {
+		lastYearTerm = strconv.Itoa(y-1) + d.InterimFinalTerm[4:]
+	}
+
+	for i := len(earlier) - 1; i >= 0; i-- {
+		e := earlier[i]
+		if e.InterimFinalCode != d.InterimFinalCode {
+			continue
+		}
+		prev, _ := jquants.ParseNumber(e.GrossDividendRate)
+		if prev == nil {
+			continue
+		}
+		switch e.InterimFinalTerm {
+		case d.InterimFinalTerm:
+			return fmt.Sprintf("[revised from %.2f]", *prev), true
+		case lastYearTerm:
+			return fmt.Sprintf("[vs last year %.2f, %s]", *prev, fmtChange(*prev, *cur)), true
+		}
+	}
+	return "", false
+}
+
+func interimFinalLabel(code string) string {
+	if code == "1" {
+		return "Interim"
+	}
+	return "Final"
+}
+
+func forecastResultLabel(code string) string {
+	if code == "2" {
+		return "Forecast"
+	}
+	return "Result"
+}
+
+func fmtDividendRate(s string) string {
+	v, err := jquants.ParseNumber(s)
+	if err != nil || v == nil {
+		return "undecided"
+	}
+	return fmt.Sprintf("%.2f", *v)
+}
+
+// 基準日時点で最新の財務諸表から、現金・有利子負債・自己資本比率をまとめる
+func (t *DividendBalanceSheetTool) balanceSheetSummary(ctx context.Context, md marketdata.DividendData, ticker string, baseDate time.Time) (string, error) {
+	details, err := md.GetFSDetails(ctx, ticker)
+	if errors.Is(err, jquants.ErrNotFound) || errors.Is(err, jquants.ErrBadRequest) {
+		details, err = nil, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch fs details: %w", err)
+	}
+
+	latest := latestFSDetails(details, baseDate)
+	if latest == nil {
+		return "Balance Sheet: N/A", nil
+	}
+
+	cash := latest.Value(cashKeys...)
+	debt := sumDebt(*latest)
+	totalAssets := latest.Value(totalAssetKeys...)
+	equity := latest.Value(equityKeys...)
+
+	parts := []string{
+		fmt.Sprintf("Cash: %s", fmtMillions(cash)),
+		fmt.Sprintf("Interest-bearing Debt: %s", fmtMillions(debt)),
+	}
+	if cash != nil && debt != nil {
+		netCash := *cash - *debt
+		parts = append(parts, fmt.Sprintf("Net Cash: %s", fmtMillions(&netCash)))
+	}
+	if equity != nil && totalAssets != nil && *totalAssets > 0 {
+		parts = append(parts, fmt.Sprintf("Equity Ratio: %.1f%%", *equity / *totalAssets * 100))
+	}
+	return fmt.Sprintf("Balance Sheet (disclosed %s): %s", latest.DisclosedDate, strings.Join(parts, " | ")), nil
+}
+
+// baseDate までに開示された最新の財務諸表。なければ nil
+func latestFSDetails(details []jquants.FSDetails, baseDate time.Time) *jquants.FSDetails {
+	base := baseDate.Format("2006-01-02")
+	var latest *jquants.FSDetails
+	for i := range details {
+		d := &details[i]
+		if d.DisclosedDate <= base && (latest == nil || d.DisclosedDate > latest.DisclosedDate) {
+			latest = d
+		}
+	}
+	return latest
+}
+
+// 有利子負債の合計。会計基準ごとの科目セットのうち最初に値が見つかったものを合計する
+func sumDebt(d jquants.FSDetails) *float64 {
+	for _, keys := range debtKeysByStandard {
+		var total float64
+		found := false
+		for _, key := range keys {
+			if v := d.Value(key); v != nil {
+				total += *v
+				found = true
+			}
+		}
+		if found {
+			return &total
+		}
+	}
+	return nil
+}
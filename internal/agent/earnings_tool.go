@@ -0,0 +1,259 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/marketdata"
+	"google.golang.org/adk/tool"
+)
+
+// -------------------------------------------------------
+// 決算サプライズ・業績予想修正ツール
+// -------------------------------------------------------
+type EarningsSurpriseArgs struct {
+	Ticker   string `json:"ticker" jsonschema:"The stock ticker symbol (e.g., '72030')."`
+	BaseDate string `json:"base_date" jsonschema:"The reference date for analysis (YYYY-MM-DD)."`
+}
+
+type EarningsSurpriseResult struct {
+	Analysis string `json:"analysis"`
+}
+
+type EarningsSurpriseTool struct {
+	Data marketdata.MarketData // 銘柄ごとの過去の決算には marketdata.StatementHistoryData も必要
+}
+
+// 四半期ごとの通期予想に対する標準的な進捗率 (%)
+var typicalProgress = map[string]float64{"1Q": 25, "2Q": 50, "3Q": 75}
+
+func (t *EarningsSurpriseTool) Execute(ctx tool.Context, args EarningsSurpriseArgs) (EarningsSurpriseResult, error) {
+	baseDate, err := time.Parse("2006-01-02", args.BaseDate)
+	if err != nil {
+		return EarningsSurpriseResult{}, fmt.Errorf("invalid date format")
+	}
+
+	md, err := marketdata.Extension[marketdata.StatementHistoryData](t.Data)
+	if err != nil {
+		return EarningsSurpriseResult{Analysis: "Earnings Surprise: N/A (not available from this data source)"}, nil
+	}
+	records, err := md.GetStatementsByCode(ctx, args.Ticker)
+	if errors.Is(err, jquants.ErrNotFound) || errors.Is(err, jquants.ErrBadRequest) {
+		return EarningsSurpriseResult{Analysis: fmt.Sprintf("Earnings Surprise: N/A (no statements for %s)", args.Ticker)}, nil
+	}
+	if err != nil {
+		return EarningsSurpriseResult{}, fmt.Errorf("failed to fetch statements: %w", err)
+	}
+	return EarningsSurpriseResult{Analysis: earningsSurprise(records, baseDate)}, nil
+}
+
+// base_date までに開示された決算を古い順に並べ、最新の決算短信を前年同期・前回予想と比べる
+func earningsSurprise(records []jquants.FinancialStatement, baseDate time.Time) string {
+	history := statementsAsOf(records, baseDate)
+	cur := latestReport(history)
+	if cur < 0 {
+		return "Earnings Surprise: N/A (no earnings report found)"
+	}
+	p := history[cur]
+	fyEnd := p.CurrentFiscalYearEndDate
+
+	lines := []string{fmt.Sprintf("Earnings Surprise (%s, fiscal year ending %s, disclosed %s):",
+		p.TypeOfCurrentPeriod, fyEnd.Format("2006-01"), p.DisclosedDate.Format("2006-01-02"))}
+
+	// 前年同期比 (累計値どうし)
+	prevYear := sameReportLastYear(history[:cur], p)
+	if prevYear != nil {
+		lines = append(lines, fmt.Sprintf("- YoY (vs %s disclosed %s): Sales %s / OpProfit %s / Profit %s",
+			p.TypeOfCurrentPeriod, prevYear.DisclosedDate.Format("2006-01-02"),
+			fmtGrowth(p.NetSales, prevYear.NetSales), fmtGrowth(p.OperatingProfit, prevYear.OperatingProfit), fmtGrowth(p.Profit, prevYear.Profit)))
+	} else {
+		lines = append(lines, "- YoY: N/A (no report for the same period last year)")
+	}
+
+	if typical, ok := typicalProgress[p.TypeOfCurrentPeriod]; ok {
+		// 四半期: 通期予想に対する進捗率を標準ペース・前年の同じ四半期と比べる
+		line := fmt.Sprintf("- Progress vs Full-Year Forecast: OpProfit %s (typical %.0f%% at %s",
+			fmtProgress(p.OperatingProfit, p.ForecastOperatingProfit), typical, p.TypeOfCurrentPeriod)
+		prevFY := findLast(history[:cur], func(s *jquants.ParsedStatement) bool {
+			return isFinancialStatement(s) && s.TypeOfCurrentPeriod == "FY" && sameMonth(s.CurrentFiscalYearEndDate, fyEnd.AddDate(-1, 0, 0))
+		})
+		if prevYear != nil && prevFY != nil {
+			line += fmt.Sprintf("; last year %s of actual", fmtProgress(prevYear.OperatingProfit, prevFY.OperatingProfit))
+		}
+		lines = append(lines, line+")")
+
+		// 前回開示時点の今期予想からの修正
+		lines = append(lines, revisionLine(history[:cur], p, fyEnd, forecastOf(p, fyEnd)))
+	} else {
+		// 通期: 実績を直前の会社予想と比べる (着地のサプライズ)
+		if prev, from := latestForecast(history[:cur], fyEnd); prev != nil {
+			lines = append(lines, fmt.Sprintf("- Actual vs Last Forecast (disclosed %s): Sales %s / OpProfit %s / Profit %s",
+				from.DisclosedDate.Format("2006-01-02"),
+				fmtVersus(p.NetSales, prev.NetSales), fmtVersus(p.OperatingProfit, prev.OperatingProfit), fmtVersus(p.Profit, prev.Profit)))
+		} else {
+			lines = append(lines, "- Actual vs Last Forecast: N/A (no earlier forecast)")
+		}
+	}
+
+	// 決算短信の後に出た業績予想の修正 (通期決算の後なら来期予想の修正)
+	target := fyEnd
+	if p.TypeOfCurrentPeriod == "FY" {
+		target = p.NextFiscalYearEndDate
+	}
+	for i := cur + 1; i < len(history); i++ {
+		if f := forecastOf(history[i], target); f != nil {
+			lines = append(lines, revisionLine(history[:i], history[i], target, f))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// base_date までに開示された決算を開示日時の古い順に並べる
+func statementsAsOf(records []jquants.FinancialStatement, baseDate time.Time) []*jquants.ParsedStatement {
+	var history []*jquants.ParsedStatement
+	for _, r := range records {
+		p, err := r.Parse()
+		if err != nil || p.DisclosedDate.After(baseDate) {
+			continue
+		}
+		history = append(history, p)
+	}
+	sort.SliceStable(history, func(i, j int) bool {
+		a, b := history[i], history[j]
+		if !a.DisclosedDate.Equal(b.DisclosedDate) {
+			return a.DisclosedDate.Before(b.DisclosedDate)
+		}
+		return a.DisclosedTime < b.DisclosedTime
+	})
+	return history
+}
+
+// history のうち最新の決算短信の位置。なければ -1
+func latestReport(history []*jquants.ParsedStatement) int {
+	for i := len(history) - 1; i >= 0; i-- {
+		if isFinancialStatement(history[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// earlier のうち p の前年同期の決算短信
+func sameReportLastYear(earlier []*jquants.ParsedStatement, p *jquants.ParsedStatement) *jquants.ParsedStatement {
+	return findLast(earlier, func(s *jquants.ParsedStatement) bool {
+		return isFinancialStatement(s) && s.TypeOfCurrentPeriod == p.TypeOfCurrentPeriod && sameMonth(s.CurrentFiscalYearEndDate, p.CurrentFiscalYearEndDate.AddDate(-1, 0, 0))
+	})
+}
+
+// 決算期末 fyEnd の通期予想 (売上・営業利益・純利益)
+type forecast struct {
+	NetSales, OperatingProfit, Profit *float64
+}
+
+// s に載っている決算期末 fyEnd の通期予想 (通期決算なら来期予想の欄)。載っていなければ nil
+func forecastOf(s *jquants.ParsedStatement, fyEnd time.Time) *forecast {
+	var f forecast
+	switch {
+	case sameMonth(s.CurrentFiscalYearEndDate, fyEnd):
+		f = forecast{s.ForecastNetSales, s.ForecastOperatingProfit, s.ForecastProfit}
+	case sameMonth(s.NextFiscalYearEndDate, fyEnd):
+		f = forecast{s.NextYearForecastNetSales, s.NextYearForecastOperatingProfit, s.NextYearForecastProfit}
+	}
+	if f.NetSales == nil && f.OperatingProfit == nil && f.Profit == nil {
+		return nil
+	}
+	return &f
+}
+
+// earlier のうち最後に fyEnd の通期予想を載せた開示
+func latestForecast(earlier []*jquants.ParsedStatement, fyEnd time.Time) (*forecast, *jquants.ParsedStatement) {
+	for i := len(earlier) - 1; i >= 0; i-- {
+		if f := forecastOf(earlier[i], fyEnd); f != nil {
+			return f, earlier[i]
+		}
+	}
+	return nil, nil
+}
+
+// s の予想 cur を、それより前の開示の予想と比べた修正の行
+func revisionLine(earlier []*jquants.ParsedStatement, s *jquants.ParsedStatement, fyEnd time.Time, cur *forecast) string {
+	label := fmt.Sprintf("%s %s", s.DisclosedDate.Format("2006-01-02"), documentLabel(s))
+	if cur == nil {
+		return fmt.Sprintf("- Forecast Revision (%s): N/A (no full-year forecast)", label)
+	}
+	prev, from := latestForecast(earlier, fyEnd)
+	if prev == nil {
+		return fmt.Sprintf("- Forecast Revision (%s): N/A (no earlier forecast)", label)
+	}
+	return fmt.Sprintf("- Forecast Revision (%s vs %s): %s | Sales %s / OpProfit %s / Profit %s",
+		label, from.DisclosedDate.Format("2006-01-02"), revisionDirection(cur.OperatingProfit, prev.OperatingProfit),
+		fmtVersus(cur.NetSales, prev.NetSales), fmtVersus(cur.OperatingProfit, prev.OperatingProfit), fmtVersus(cur.Profit, prev.Profit))
+}
+
+// 営業利益予想の修正方向
+func revisionDirection(cur, prev *float64) string {
+	switch {
+	case cur == nil || prev == nil:
+		return "N/A"
+	case *cur > *prev:
+		return "UPWARD"
+	case *cur < *prev:
+		return "DOWNWARD"
+	}
+	return "UNCHANGED"
+}
+
+func isFinancialStatement(s *jquants.ParsedStatement) bool {
+	return strings.Contains(s.TypeOfDocument, "FinancialStatements")
+}
+
+// 決算短信は四半期、それ以外 (業績予想の修正など) は書類名をそのまま出す
+func documentLabel(s *jquants.ParsedStatement) string {
+	if isFinancialStatement(s) {
+		return s.TypeOfCurrentPeriod + " report"
+	}
+	return s.TypeOfDocument
+}
+
+func findLast(items []*jquants.ParsedStatement, match func(*jquants.ParsedStatement) bool) *jquants.ParsedStatement {
+	for i := len(items) - 1; i >= 0; i-- {
+		if match(items[i]) {
+			return items[i]
+		}
+	}
+	return nil
+}
+
+// 決算期末は月末なので年月で比べる (2月末の閏年などで日がずれても同じ期とみなす)
+func sameMonth(a, b time.Time) bool {
+	return !a.IsZero() && a.Year() == b.Year() && a.Month() == b.Month()
+}
+
+// "+12.3%"。前年が赤字など比べられない場合は金額を並べる
+func fmtGrowth(v, base *float64) string {
+	if g, ok := growthPct(v, base); ok {
+		return fmt.Sprintf("%+.1f%%", g)
+	}
+	return fmt.Sprintf("N/A (%s vs %s)", fmtMillions(v), fmtMillions(base))
+}
+
+// 比較対象と金額を並べて変化率を添える
+func fmtVersus(v, base *float64) string {
+	s := fmt.Sprintf("%s vs %s", fmtMillions(v), fmtMillions(base))
+	if g, ok := growthPct(v, base); ok {
+		s += fmt.Sprintf(" (%+.1f%%)", g)
+	}
+	return s
+}
+
+// 予想・実績 total に対する v の割合
+func fmtProgress(v, total *float64) string {
+	if v == nil || total == nil || *total <= 0 {
+		return "N/A"
+	}
+	return fmt.Sprintf("%.1f%%", *v / *total * 100)
+}
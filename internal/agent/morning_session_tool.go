@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/calendar"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/marketdata"
+	"google.golang.org/adk/tool"
+)
+
+// -------------------------------------------------------
+// 前場 (ザラ場中・昼休みの開示向け) ツール
+// -------------------------------------------------------
+type MorningSessionArgs struct {
+	Ticker   string `json:"ticker" jsonschema:"The stock ticker symbol (e.g., '72030')."`
+	BaseDate string `json:"base_date" jsonschema:"The disclosure date (YYYY-MM-DD). The morning session of this day is returned."`
+}
+
+type MorningSessionResult struct {
+	Analysis string `json:"analysis"`
+}
+
+type MorningSessionTool struct {
+	Data     marketdata.MarketData // 当日の前場には marketdata.MorningSessionData も必要
+	Calendar *calendar.Calendar    // nil の場合は暦日で近似する
+	Mode     jquants.PriceMode     // 空の場合は分割調整済み
+}
+
+// 今日の日付を決める時計 (テストで差し替える)
+var timeNow = time.Now
+
+func (t *MorningSessionTool) Execute(ctx tool.Context, args MorningSessionArgs) (MorningSessionResult, error) {
+	resultStr, err := t.morningSessionLogic(ctx, args.Ticker, args.BaseDate)
+	if err != nil {
+		return MorningSessionResult{}, err
+	}
+	return MorningSessionResult{Analysis: resultStr}, nil
+}
+
+// 開示日の前場を前営業日の終値と比べる
+// 昼の開示は後場から織り込まれるので、後場・大引けの値はあえて返さない
+func (t *MorningSessionTool) morningSessionLogic(ctx context.Context, ticker string, baseDateStr string) (string, error) {
+	baseDate, err := time.Parse("2006-01-02", baseDateStr)
+	if err != nil {
+		return "", fmt.Errorf("invalid date format")
+	}
+
+	from := baseDate.AddDate(0, 0, -7)
+	if t.Calendar != nil {
+		if !t.Calendar.IsTradingDay(baseDate) {
+			return fmt.Sprintf("Morning Session: market was closed on %s.", baseDateStr), nil
+		}
+		from, err = t.Calendar.PrevTradingDay(baseDate)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	mode := t.Mode
+	if mode == "" {
+		mode = jquants.PriceAdjusted
+	}
+	quotes, err := t.Data.GetDailyQuotesWithMode(ctx, ticker, from.Format("2006-01-02"), baseDateStr, mode)
+	if errors.Is(err, jquants.ErrNotFound) || errors.Is(err, jquants.ErrBadRequest) {
+		return fmt.Sprintf("Price data unavailable for %s as of %s.", ticker, baseDateStr), nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch quotes: %w", err)
+	}
+
+	var prev *jquants.DailyQuote
+	var am jquants.MorningQuote
+	found := false
+	for i, q := range quotes {
+		if q.Date == baseDateStr {
+			am, found = q.MorningSession()
+		} else {
+			prev = &quotes[i]
+		}
+	}
+
+	// 当日分はまだ日足に載っていないので /prices/prices_am から取る (当日の値なので生値=調整済み)
+	// prices_am は常に今日の前場しか返さないので、過去日 (バックテストなど) では使わない
+	// (取得元が前場を提供していなければ N/A)
+	today := timeNow().In(calendar.JST).Format("2006-01-02")
+	if src, err := marketdata.Extension[marketdata.MorningSessionData](t.Data); err == nil && !found && baseDateStr == today {
+		live, err := src.GetPricesAM(ctx, ticker)
+		if err != nil && !errors.Is(err, jquants.ErrNotFound) && !errors.Is(err, jquants.ErrBadRequest) {
+			return "", fmt.Errorf("failed to fetch morning session: %w", err)
+		}
+		for _, q := range live {
+			if q.Date == baseDateStr && q.MorningOpen > 0 {
+				am, found = q, true
+			}
+		}
+	}
+	if !found {
+		return fmt.Sprintf("Morning Session: N/A for %s on %s (no morning session data).", ticker, baseDateStr), nil
+	}
+
+	lines := fmt.Sprintf("Morning Session (%s): Open %.0f / High %.0f / Low %.0f / Close %.0f",
+		am.Date, am.MorningOpen, am.MorningHigh, am.MorningLow, am.MorningClose)
+	if prev != nil {
+		lines += fmt.Sprintf("\nMorning Change: %s vs Prev Close %.0f (Open Gap: %s)",
+			fmtChange(prev.Close, am.MorningClose), prev.Close, fmtChange(prev.Close, am.MorningOpen))
+	}
+	lines += fmt.Sprintf("\nMorning Trading Value: %.0f JPY (Volume: %.0f)", am.MorningTurnoverValue, am.MorningVolume)
+	return lines, nil
+}
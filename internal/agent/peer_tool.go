@@ -0,0 +1,275 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/calendar"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/marketdata"
+	"google.golang.org/adk/tool"
+)
+
+// -------------------------------------------------------
+// 同業他社比較ツール
+// -------------------------------------------------------
+type SectorPeerArgs struct {
+	Ticker   string `json:"ticker" jsonschema:"The stock ticker symbol (e.g., '72030')."`
+	BaseDate string `json:"base_date" jsonschema:"The reference date for analysis (YYYY-MM-DD)."`
+}
+
+type SectorPeerResult struct {
+	Analysis string `json:"analysis"`
+}
+
+type SectorPeerTool struct {
+	Data     marketdata.MarketData // 成長率・PER・PBR には marketdata.StatementHistoryData も必要 (なければ騰落率だけ比べる)
+	Calendar *calendar.Calendar    // nil の場合は暦日で近似する
+}
+
+const (
+	maxPeers = 10 // 比べる同業他社の上限 (規模の近い順)
+	minPeers = 3  // 33業種の同業がこれより少なければ17業種に広げる
+)
+
+// TOPIX の規模区分 (大きい順)。どれにも入らない銘柄は最後
+var scaleOrder = []string{"TOPIX Core30", "TOPIX Large70", "TOPIX Mid400", "TOPIX Small 1", "TOPIX Small 2"}
+
+// 銘柄ごとの比較項目 (取れなかった項目は nil)
+type peerMetrics struct {
+	Info        jquants.ListedInfo
+	SalesGrowth *float64 // 最新の決算短信の前年同期比 (%)
+	OpGrowth    *float64
+	PER         *float64 // 予想PER
+	PBR         *float64
+	Return      *float64 // 直近 trendSessions 営業日の騰落率 (%)
+}
+
+func (t *SectorPeerTool) Execute(ctx tool.Context, args SectorPeerArgs) (SectorPeerResult, error) {
+	return t.compare(ctx, args)
+}
+
+func (t *SectorPeerTool) compare(ctx context.Context, args SectorPeerArgs) (SectorPeerResult, error) {
+	baseDate, err := time.Parse("2006-01-02", args.BaseDate)
+	if err != nil {
+		return SectorPeerResult{}, fmt.Errorf("invalid date format")
+	}
+
+	infos, err := t.Data.GetListedInfo(ctx, "", args.BaseDate)
+	if errors.Is(err, jquants.ErrNotFound) || errors.Is(err, jquants.ErrBadRequest) {
+		return SectorPeerResult{Analysis: fmt.Sprintf("Sector Peers: N/A (listed info unavailable as of %s)", args.BaseDate)}, nil
+	}
+	if err != nil {
+		return SectorPeerResult{}, fmt.Errorf("failed to fetch listed info: %w", err)
+	}
+	code := jquants.NormalizeCode(args.Ticker)
+	i := slices.IndexFunc(infos, func(info jquants.ListedInfo) bool { return info.Code == code })
+	if i < 0 {
+		return SectorPeerResult{Analysis: fmt.Sprintf("Sector Peers: N/A (%s not found in listed info)", args.Ticker)}, nil
+	}
+	self := infos[i]
+	peers, group := peerGroup(infos, self)
+	if len(peers) == 0 {
+		return SectorPeerResult{Analysis: fmt.Sprintf("Sector Peers: N/A (no listed peers in %s)", self.Sector33CodeName)}, nil
+	}
+
+	from := baseDate.AddDate(0, 0, -trendSessions*7/5)
+	if t.Calendar != nil {
+		from, err = t.Calendar.SessionsBack(baseDate, trendSessions)
+		if err != nil {
+			return SectorPeerResult{}, err
+		}
+	}
+	// 決算の履歴が取れないデータ取得元では騰落率だけ比べる
+	statements, _ := marketdata.Extension[marketdata.StatementHistoryData](t.Data)
+
+	stock, err := t.metrics(ctx, statements, self, from, baseDate)
+	if err != nil {
+		return SectorPeerResult{}, err
+	}
+	var others []peerMetrics
+	for _, info := range peers {
+		m, err := t.metrics(ctx, statements, info, from, baseDate)
+		if err != nil {
+			return SectorPeerResult{}, err
+		}
+		others = append(others, m)
+	}
+	return SectorPeerResult{Analysis: formatPeers(stock, others, group, args.BaseDate)}, nil
+}
+
+// 同じ33業種の銘柄 (少なければ17業種に広げる) を規模の近い順に maxPeers まで
+// 「その他」(ETF など) は業種として比べられないので対象外
+func peerGroup(infos []jquants.ListedInfo, self jquants.ListedInfo) ([]jquants.ListedInfo, string) {
+	if self.Sector33Code == "" || self.Sector33Code == "9999" {
+		return nil, ""
+	}
+	same := func(match func(jquants.ListedInfo) bool) []jquants.ListedInfo {
+		var out []jquants.ListedInfo
+		for _, info := range infos {
+			if info.Code != self.Code && match(info) {
+				out = append(out, info)
+			}
+		}
+		return out
+	}
+	peers := same(func(info jquants.ListedInfo) bool { return info.Sector33Code == self.Sector33Code })
+	group := fmt.Sprintf("Sector33 %s", self.Sector33CodeName)
+	if len(peers) < minPeers && self.Sector17Code != "99" {
+		if wider := same(func(info jquants.ListedInfo) bool { return info.Sector17Code == self.Sector17Code }); len(wider) > len(peers) {
+			peers, group = wider, fmt.Sprintf("Sector17 %s", self.Sector17CodeName)
+		}
+	}
+
+	rank := func(info jquants.ListedInfo) int {
+		if i := slices.Index(scaleOrder, info.ScaleCategory); i >= 0 {
+			return i
+		}
+		return len(scaleOrder)
+	}
+	distance := func(info jquants.ListedInfo) int {
+		return max(rank(info)-rank(self), rank(self)-rank(info))
+	}
+	sort.SliceStable(peers, func(i, j int) bool {
+		a, b := peers[i], peers[j]
+		if distance(a) != distance(b) {
+			return distance(a) < distance(b)
+		}
+		if rank(a) != rank(b) {
+			return rank(a) < rank(b)
+		}
+		return a.Code < b.Code
+	})
+	return peers[:min(len(peers), maxPeers)], group
+}
+
+// 1銘柄の比較項目を計算する。データがない項目は nil のまま (認証エラーやキャンセルだけはエラーにする)
+func (t *SectorPeerTool) metrics(ctx context.Context, statements marketdata.StatementHistoryData, info jquants.ListedInfo, from, baseDate time.Time) (peerMetrics, error) {
+	m := peerMetrics{Info: info}
+
+	var history []*jquants.ParsedStatement
+	if statements != nil {
+		records, err := statements.GetStatementsByCode(ctx, info.Code)
+		if err != nil && (ctx.Err() != nil || errors.Is(err, jquants.ErrUnauthorized)) {
+			return m, fmt.Errorf("failed to fetch statements of %s: %w", info.Code, err)
+		}
+		history = statementsAsOf(records, baseDate)
+	}
+	if cur := latestReport(history); cur >= 0 {
+		p := history[cur]
+		if prev := sameReportLastYear(history[:cur], p); prev != nil {
+			m.SalesGrowth = optional(growthPct(p.NetSales, prev.NetSales))
+			m.OpGrowth = optional(growthPct(p.OperatingProfit, prev.OperatingProfit))
+		}
+	}
+	eps, epsFrom := forwardEPS(history)
+	bps, bpsFrom := latestBookValue(history)
+
+	// 騰落率は分割調整済み、PER・PBR は生の終値で計算する (決算の1株当たりの値はその後の分割を反映する)
+	since, _ := earliestDisclosure(epsFrom, bpsFrom)
+	quotes, err := rawQuotesSince(ctx, t.Data, info.Code, since, from, baseDate)
+	if err != nil && (ctx.Err() != nil || errors.Is(err, jquants.ErrUnauthorized)) {
+		return m, fmt.Errorf("failed to fetch quotes of %s: %w", info.Code, err)
+	}
+	// base_date に売買がなければ、騰落率・PER・PBR とも直前の終値のある日で計算する
+	quotes = untilLastTrade(quotes)
+	if len(quotes) == 0 {
+		return m, nil
+	}
+	fromDate := from.Format("2006-01-02")
+	if i := slices.IndexFunc(quotes, func(q jquants.DailyQuote) bool { return q.Date >= fromDate && q.Close > 0 }); i >= 0 && len(quotes)-i >= 2 {
+		first, last := quotes[i].In(jquants.PriceAdjusted), quotes[len(quotes)-1].In(jquants.PriceAdjusted)
+		r := (last.Close - first.Close) / first.Close * 100
+		m.Return = &r
+	}
+	price := quotes[len(quotes)-1].Close
+	m.PER = optional(multiple(price, perShareAsOf(eps, epsFrom, quotes)))
+	m.PBR = optional(multiple(price, perShareAsOf(bps, bpsFrom, quotes)))
+	return m, nil
+}
+
+func optional(v float64, ok bool) *float64 {
+	if !ok {
+		return nil
+	}
+	return &v
+}
+
+func formatPeers(stock peerMetrics, peers []peerMetrics, group string, baseDate string) string {
+	lines := []string{
+		fmt.Sprintf("Sector Peers (%s, %d peers closest in size, as of %s):", group, len(peers), baseDate),
+		"Rank 1 = fastest growth / cheapest valuation / best performance.",
+	}
+	pct := func(v float64) string { return fmt.Sprintf("%+.1f%%", v) }
+	times := func(v float64) string { return fmt.Sprintf("%.1fx", v) }
+	metrics := []struct {
+		name          string
+		value         func(peerMetrics) *float64
+		format        func(float64) string
+		lowerIsBetter bool
+	}{
+		{"Sales YoY", func(m peerMetrics) *float64 { return m.SalesGrowth }, pct, false},
+		{"OpProfit YoY", func(m peerMetrics) *float64 { return m.OpGrowth }, pct, false},
+		{"Forward PER", func(m peerMetrics) *float64 { return m.PER }, times, true},
+		{"PBR", func(m peerMetrics) *float64 { return m.PBR }, times, true},
+		{fmt.Sprintf("%dd Return", trendSessions), func(m peerMetrics) *float64 { return m.Return }, pct, false},
+	}
+
+	for _, metric := range metrics {
+		var values []float64
+		for _, p := range peers {
+			if v := metric.value(p); v != nil {
+				values = append(values, *v)
+			}
+		}
+		median := "N/A"
+		if len(values) > 0 {
+			median = metric.format(medianOf(values))
+		}
+		v := metric.value(stock)
+		switch {
+		case v == nil:
+			lines = append(lines, fmt.Sprintf("- %s: N/A (peer median %s)", metric.name, median))
+			continue
+		case len(values) == 0:
+			lines = append(lines, fmt.Sprintf("- %s: %s (no peer data)", metric.name, metric.format(*v)))
+			continue
+		}
+		// 自社を含めた順位 (値が取れた銘柄の中で)
+		rank := 1
+		for _, pv := range values {
+			if (metric.lowerIsBetter && pv < *v) || (!metric.lowerIsBetter && pv > *v) {
+				rank++
+			}
+		}
+		lines = append(lines, fmt.Sprintf("- %s: %s (rank %d of %d, peer median %s)",
+			metric.name, metric.format(*v), rank, len(values)+1, median))
+	}
+
+	lines = append(lines, "Peers:")
+	for _, p := range peers {
+		parts := make([]string, len(metrics))
+		for i, metric := range metrics {
+			parts[i] = metric.name + " N/A"
+			if v := metric.value(p); v != nil {
+				parts[i] = metric.name + " " + metric.format(*v)
+			}
+		}
+		lines = append(lines, fmt.Sprintf("- %s %s (%s): %s", p.Info.Code, p.Info.CompanyName, p.Info.ScaleCategory, strings.Join(parts, " / ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func medianOf(values []float64) float64 {
+	s := slices.Clone(values)
+	slices.Sort(s)
+	if n := len(s); n%2 == 0 {
+		return (s[n/2-1] + s[n/2]) / 2
+	}
+	return s[len(s)/2]
+}
@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/marketdata"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/regime"
+	"google.golang.org/adk/tool"
+)
+
+// -------------------------------------------------------
+// 市場地合い (海外投資家フロー) ツール
+// -------------------------------------------------------
+type MarketRegimeArgs struct {
+	BaseDate string `json:"base_date" jsonschema:"The reference date for analysis (YYYY-MM-DD)."`
+}
+
+type MarketRegimeResult struct {
+	Analysis string `json:"analysis"`
+}
+
+type MarketRegimeTool struct {
+	Data    marketdata.MarketData // marketdata.TradesSpecData も必要
+	Section string                // 空なら東証プライム
+}
+
+func (t *MarketRegimeTool) Execute(ctx tool.Context, args MarketRegimeArgs) (MarketRegimeResult, error) {
+	baseDate, err := time.Parse("2006-01-02", args.BaseDate)
+	if err != nil {
+		return MarketRegimeResult{}, fmt.Errorf("invalid date format")
+	}
+
+	section := t.Section
+	if section == "" {
+		section = jquants.SectionTSEPrime
+	}
+	flow, err := regime.ForeignFlowRegime(ctx, t.Data, section, baseDate, regime.DefaultWeeks)
+	if errors.Is(err, marketdata.ErrUnsupported) {
+		return MarketRegimeResult{Analysis: "Market Regime: N/A (investor flow data not available from this data source)"}, nil
+	}
+	if err != nil {
+		return MarketRegimeResult{}, err
+	}
+	return MarketRegimeResult{Analysis: flow.String()}, nil
+}
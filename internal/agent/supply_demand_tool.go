@@ -0,0 +1,149 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/calendar"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/marketdata"
+	"google.golang.org/adk/tool"
+)
+
+// -------------------------------------------------------
+// 需給 (信用残・空売り比率) ツール
+// -------------------------------------------------------
+type SupplyDemandArgs struct {
+	Ticker   string `json:"ticker" jsonschema:"The stock ticker symbol (e.g., '72030')."`
+	BaseDate string `json:"base_date" jsonschema:"The reference date for analysis (YYYY-MM-DD)."`
+}
+
+type SupplyDemandResult struct {
+	Analysis string `json:"analysis"`
+}
+
+type SupplyDemandTool struct {
+	Data     marketdata.MarketData // 信用残・空売り比率には marketdata.MarginData も必要
+	Calendar *calendar.Calendar    // nil の場合は暦日で近似する
+}
+
+const (
+	// 信用残は週次なので8週分を見る
+	marginLookbackWeeks = 8
+	// 空売り比率は直近5営業日の平均を20営業日平均と比べる
+	shortSellingRecentSessions = 5
+)
+
+func (t *SupplyDemandTool) Execute(ctx tool.Context, args SupplyDemandArgs) (SupplyDemandResult, error) {
+	baseDate, err := time.Parse("2006-01-02", args.BaseDate)
+	if err != nil {
+		return SupplyDemandResult{}, fmt.Errorf("invalid date format")
+	}
+
+	md, err := marketdata.Extension[marketdata.MarginData](t.Data)
+	if err != nil {
+		return SupplyDemandResult{Analysis: "Supply/Demand: N/A (margin and short selling data not available from this data source)"}, nil
+	}
+
+	margin, err := t.marginSummary(ctx, md, args.Ticker, baseDate)
+	if err != nil {
+		return SupplyDemandResult{}, err
+	}
+	short, err := t.shortSellingSummary(ctx, md, args.Ticker, baseDate)
+	if err != nil {
+		return SupplyDemandResult{}, err
+	}
+	return SupplyDemandResult{Analysis: margin + "\n" + short}, nil
+}
+
+// 信用残の推移 (最新の信用倍率と、数週間前からの買残・売残の増減)
+func (t *SupplyDemandTool) marginSummary(ctx context.Context, md marketdata.MarginData, ticker string, baseDate time.Time) (string, error) {
+	fromDate := baseDate.AddDate(0, 0, -7*marginLookbackWeeks).Format("2006-01-02")
+	records, err := md.GetWeeklyMarginInterest(ctx, ticker, fromDate, baseDate.Format("2006-01-02"))
+	if errors.Is(err, jquants.ErrNotFound) || errors.Is(err, jquants.ErrBadRequest) || (err == nil && len(records) == 0) {
+		return "Margin Trading: N/A (not a margin-eligible stock or no data)", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch margin interest: %w", err)
+	}
+
+	latest, oldest := records[len(records)-1], records[0]
+	ratio := "N/A"
+	if r, ok := latest.MarginRatio(); ok {
+		ratio = fmt.Sprintf("%.2fx", r)
+	}
+
+	lines := []string{
+		fmt.Sprintf("Margin Trading (as of %s): Long %.0f / Short %.0f shares, Margin Ratio %s",
+			latest.Date, latest.LongMarginTradeVolume, latest.ShortMarginTradeVolume, ratio),
+	}
+	if len(records) > 1 {
+		lines = append(lines, fmt.Sprintf("Margin Trend (%d weeks since %s): Long %s, Short %s",
+			len(records)-1, oldest.Date,
+			fmtChange(oldest.LongMarginTradeVolume, latest.LongMarginTradeVolume),
+			fmtChange(oldest.ShortMarginTradeVolume, latest.ShortMarginTradeVolume)))
+		if r0, ok0 := oldest.MarginRatio(); ok0 {
+			if r1, ok1 := latest.MarginRatio(); ok1 {
+				lines = append(lines, fmt.Sprintf("Margin Ratio Trend: %.2fx -> %.2fx", r0, r1))
+			}
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// 業種全体の空売り比率 (J-Quants は銘柄単位の空売り比率を提供していないため業種単位)
+func (t *SupplyDemandTool) shortSellingSummary(ctx context.Context, md marketdata.MarginData, ticker string, baseDate time.Time) (string, error) {
+	info, err := t.Data.GetCompanyInfo(ctx, ticker)
+	if errors.Is(err, jquants.ErrNotFound) {
+		return "Sector Short Selling: N/A (unknown sector)", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch listed info: %w", err)
+	}
+
+	from := baseDate.AddDate(0, 0, -trendSessions*7/5)
+	if t.Calendar != nil {
+		from, err = t.Calendar.SessionsBack(baseDate, trendSessions)
+		if err != nil {
+			return "", err
+		}
+	}
+	records, err := md.GetShortSelling(ctx, info.Sector33Code, from.Format("2006-01-02"), baseDate.Format("2006-01-02"))
+	if errors.Is(err, jquants.ErrBadRequest) || (err == nil && len(records) == 0) {
+		return fmt.Sprintf("Sector Short Selling (%s): N/A", info.Sector33CodeName), nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch short selling: %w", err)
+	}
+
+	recentAvg := avgShortSellingRatio(records[max(0, len(records)-shortSellingRecentSessions):])
+	periodAvg := avgShortSellingRatio(records)
+	return fmt.Sprintf("Sector Short Selling Ratio (%s): %.1f%% (last %d sessions avg) vs %.1f%% (%d sessions avg)",
+		info.Sector33CodeName, recentAvg, min(len(records), shortSellingRecentSessions), periodAvg, len(records)), nil
+}
+
+func avgShortSellingRatio(records []jquants.ShortSelling) float64 {
+	var total float64
+	count := 0
+	for _, r := range records {
+		if ratio, ok := r.ShortSellingRatio(); ok {
+			total += ratio
+			count++
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return total / float64(count)
+}
+
+// from から to への増減率 ("+12.3%" 形式)
+func fmtChange(from, to float64) string {
+	if from <= 0 {
+		return "N/A"
+	}
+	return fmt.Sprintf("%+.1f%%", (to-from)/from*100)
+}
@@ -1,10 +1,15 @@
 package agent
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"time"
 
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/calendar"
 	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/marketdata"
 	"google.golang.org/adk/tool"
 )
 
@@ -17,43 +22,101 @@ type PriceTrendArgs struct {
 }
 
 type PriceTrendResult struct {
-	Analysis string `json:"analysis"` // User定義のGetPriceTrendが返す文字列を格納
+	Analysis string `json:"analysis"` // モデル向けに数値と指標を読みやすく整形した文字列
+
+	// 集計やダッシュボード用の数値 (データが取れなかった場合は Trend が空で、ほかもゼロ値)
+	Trend            string  `json:"trend,omitempty"`              // UPTREND / FLAT / DOWNTREND
+	ChangePct        float64 `json:"change_pct,omitempty"`         // SessionsUsed 営業日の騰落率 (%)
+	AvgValueJPY      float64 `json:"avg_value_jpy,omitempty"`      // 直近5日の平均売買代金 (円)
+	AvgVolatilityPct float64 `json:"avg_volatility_pct,omitempty"` // 直近5日の平均変動率 ((高値-安値)/始値, %)
+	LatestClose      float64 `json:"latest_close,omitempty"`
+	SessionsUsed     int     `json:"sessions_used,omitempty"` // 騰落率を計算した営業日数
 }
 
 // -------------------------------------------------------
 // 2. Toolの実体 (依存関係を持つ構造体)
 // -------------------------------------------------------
 type PriceTrendTool struct {
-	Client *jquants.Client
+	Data     marketdata.MarketData
+	Calendar *calendar.Calendar // nil の場合は暦日で近似する
+	Mode     jquants.PriceMode  // 空の場合は分割調整済み (分割を挟んでも変化率が崩れないように)
+
+	Indicators []string // 結果に付けるテクニカル指標 (AllIndicators の中から。空なら付けない)
 }
 
+// トレンド判定に使う営業日数
+const trendSessions = 20
+
 // ADKから呼ばれるハンドラメソッド
 func (t *PriceTrendTool) Execute(ctx tool.Context, args PriceTrendArgs) (PriceTrendResult, error) {
 	// 既存のロジックを呼び出す
-	resultStr, err := t.getPriceTrendLogic(args.Ticker, args.BaseDate)
-	if err != nil {
-		return PriceTrendResult{}, err
-	}
-	return PriceTrendResult{Analysis: resultStr}, nil
+	// tool.Context は context.Context を満たすので、そのまま渡してキャンセルを伝播させる
+	return t.getPriceTrendLogic(ctx, args.Ticker, args.BaseDate)
 }
 
-func (t *PriceTrendTool) getPriceTrendLogic(ticker string, baseDateStr string) (string, error) {
+// エージェントを介さずに直接トレンドを計算する (監視リスト作成など)
+func (t *PriceTrendTool) Trend(ctx context.Context, ticker string, baseDate string) (PriceTrendResult, error) {
+	return t.getPriceTrendLogic(ctx, ticker, baseDate)
+}
+
+func (t *PriceTrendTool) getPriceTrendLogic(ctx context.Context, ticker string, baseDateStr string) (PriceTrendResult, error) {
 	baseDate, err := time.Parse("2006-01-02", baseDateStr)
 	if err != nil {
-		return "", fmt.Errorf("invalid date format")
+		return PriceTrendResult{}, fmt.Errorf("invalid date format")
 	}
 
-	fromDate := baseDate.AddDate(0, 0, -20).Format("2006-01-02")
+	// 祝日や週末を挟んでも常に同じ営業日数で比較できるよう、カレンダーで遡る
+	from := baseDate.AddDate(0, 0, -trendSessions*7/5)
+	if t.Calendar != nil {
+		from, err = t.Calendar.SessionsBack(baseDate, trendSessions)
+		if err != nil {
+			return PriceTrendResult{}, err
+		}
+	}
+	fromDate := from.Format("2006-01-02")
 	toDate := baseDateStr
 
-	quotes, err := t.Client.GetDailyQuotes(ticker, fromDate, toDate)
+	// テクニカル指標の分はさらに遡って取る (カレンダーの範囲外は暦日で近似)
+	historyFrom := from
+	if lookback := indicatorLookback(t.Indicators); lookback > trendSessions {
+		historyFrom = baseDate.AddDate(0, 0, -lookback*3/2)
+		if t.Calendar != nil {
+			if d, err := t.Calendar.SessionsBack(baseDate, lookback); err == nil {
+				historyFrom = d
+			} else if !errors.Is(err, calendar.ErrOutOfRange) {
+				return PriceTrendResult{}, err
+			}
+		}
+	}
+
+	mode := t.Mode
+	if mode == "" {
+		mode = jquants.PriceAdjusted
+	}
+	history, err := t.Data.GetDailyQuotesWithMode(ctx, ticker, historyFrom.Format("2006-01-02"), toDate, mode)
+	if err != nil && historyFrom.Before(from) && ctx.Err() == nil && !errors.Is(err, jquants.ErrUnauthorized) &&
+		!errors.Is(err, jquants.ErrNotFound) && !errors.Is(err, jquants.ErrBadRequest) {
+		// 同期期間の短いローカルデータストアなどは長い期間を取れないので、トレンドの期間だけで計算する (指標は N/A になる)
+		// キャンセルや認証エラーは取り直しても同じなので、そのまま返す
+		history, err = t.Data.GetDailyQuotesWithMode(ctx, ticker, fromDate, toDate, mode)
+	}
+	if errors.Is(err, jquants.ErrNotFound) || errors.Is(err, jquants.ErrBadRequest) {
+		// 銘柄コードや日付の誤りはモデル側で判断できるよう、エラーではなく結果として返す
+		return PriceTrendResult{Analysis: fmt.Sprintf("Price data unavailable for %s as of %s.", ticker, baseDateStr)}, nil
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch quotes: %v", err)
+		return PriceTrendResult{}, fmt.Errorf("failed to fetch quotes: %w", err)
+	}
+	// 売買が成立しなかった日は四本値が 0 (null) なので、騰落率・流動性・変動率・指標のどれにも使わない
+	history = tradedQuotes(history)
+	// トレンド・流動性・変動率は直近 trendSessions 営業日で見る
+	var quotes []jquants.DailyQuote
+	if i := slices.IndexFunc(history, func(q jquants.DailyQuote) bool { return q.Date >= fromDate }); i >= 0 {
+		quotes = history[i:]
 	}
 	if len(quotes) < 5 {
-		return "Insufficient data (less than 5 days).", nil
+		return PriceTrendResult{Analysis: fmt.Sprintf("Insufficient data (less than 5 trading days for %s as of %s).", ticker, baseDateStr)}, nil
 	}
-
 	latest := quotes[len(quotes)-1]
 	start := quotes[0]
 
@@ -70,10 +133,8 @@ func (t *PriceTrendTool) getPriceTrendLogic(ticker string, baseDateStr string) (
 		if basePrice == 0 {
 			basePrice = q.Close
 		}
-		if basePrice > 0 {
-			dayRange := (q.High - q.Low) / basePrice * 100
-			totalVolatility += dayRange
-		}
+		dayRange := (q.High - q.Low) / basePrice * 100
+		totalVolatility += dayRange
 		count++
 	}
 
@@ -90,8 +151,17 @@ func (t *PriceTrendTool) getPriceTrendLogic(ticker string, baseDateStr string) (
 	}
 
 	// === 判定なし。事実のみを返す ===
-	return fmt.Sprintf(
-		"Trend: %s (Change: %.2f%% in 20days)\nAvg Trading Value: %.0f JPY\nAvg Daily Volatility: %.2f%%\nLatest Close: %.0f",
-		trend, changeRate, avgValue, avgVolatility, latest.Close,
-	), nil
+	r := PriceTrendResult{
+		Trend:            trend,
+		ChangePct:        changeRate,
+		AvgValueJPY:      avgValue,
+		AvgVolatilityPct: avgVolatility,
+		LatestClose:      latest.Close,
+		SessionsUsed:     len(quotes) - 1,
+	}
+	r.Analysis = fmt.Sprintf(
+		"Trend: %s (Change: %.2f%% in %d sessions)\nAvg Trading Value: %.0f JPY\nAvg Daily Volatility: %.2f%%\nLatest Close: %.0f",
+		r.Trend, r.ChangePct, r.SessionsUsed, r.AvgValueJPY, r.AvgVolatilityPct, r.LatestClose,
+	) + formatIndicators(history, t.Indicators)
+	return r, nil
 }
@@ -0,0 +1,298 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/calendar"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquantstest"
+)
+
+func TestPriceTrendToolFixtures(t *testing.T) {
+	fx := jquantstest.DefaultFixtures()
+	cal := calendar.New(fx.TradingCalendar)
+
+	// 39990 は 2025-07-01 に1:2の分割。2025-06-12 (20営業日前) の終値は 1531 (調整後 765.5)、2025-07-10 は 758
+	tests := []struct {
+		name       string
+		ticker     string
+		mode       jquants.PriceMode
+		wantTrend  string
+		wantChange float64
+		wantClose  float64
+	}{
+		{"adjusted across split", "39990", jquants.PriceAdjusted, "FLAT", (758 - 765.5) / 765.5 * 100, 758},
+		{"raw across split", "39990", jquants.PriceRaw, "DOWNTREND", (758.0 - 1531) / 1531 * 100, 758},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := &PriceTrendTool{Data: fx, Calendar: cal, Mode: tt.mode}
+			r, err := tool.Trend(context.Background(), tt.ticker, "2025-07-10")
+			if err != nil {
+				t.Fatalf("Trend: %v", err)
+			}
+			if r.Trend != tt.wantTrend || math.Abs(r.ChangePct-tt.wantChange) > 1e-9 {
+				t.Errorf("trend = %s %.4f%%, want %s %.4f%%", r.Trend, r.ChangePct, tt.wantTrend, tt.wantChange)
+			}
+			if r.LatestClose != tt.wantClose || r.SessionsUsed != trendSessions {
+				t.Errorf("latest close = %v over %d sessions, want %v over %d", r.LatestClose, r.SessionsUsed, tt.wantClose, trendSessions)
+			}
+		})
+	}
+
+	t.Run("unknown ticker", func(t *testing.T) {
+		r, err := (&PriceTrendTool{Data: fx, Calendar: cal}).Trend(context.Background(), "99999", "2025-07-10")
+		if err != nil {
+			t.Fatalf("Trend: %v", err)
+		}
+		if r.Trend != "" || r.Analysis == "" {
+			t.Errorf("got %+v, want N/A analysis without trend", r)
+		}
+	})
+}
+
+// 売買が成立しなかった日 (四本値が null で 0 になる) を挟んでも騰落率が Inf/NaN にならない
+func TestPriceTrendToolNoTradeDays(t *testing.T) {
+	noTrade := func(fx *jquantstest.Fixtures, code string, dates ...string) {
+		for i, q := range fx.DailyQuotes {
+			if q.Code == code && slices.Contains(dates, q.Date) {
+				fx.DailyQuotes[i] = jquants.DailyQuote{Date: q.Date, Code: q.Code, AdjustmentFactor: 1}
+			}
+		}
+	}
+
+	// 20営業日前 (2025-06-12) と base_date (2025-07-10) に売買なし → 2025-06-13 の 765.5 から 2025-07-09 の 764 まで
+	fx := jquantstest.DefaultFixtures()
+	noTrade(fx, "39990", "2025-06-12", "2025-07-10")
+	tool := &PriceTrendTool{Data: fx, Calendar: calendar.New(fx.TradingCalendar), Indicators: AllIndicators}
+	r, err := tool.Trend(context.Background(), "39990", "2025-07-10")
+	if err != nil {
+		t.Fatalf("Trend: %v", err)
+	}
+	if want := (764 - 765.5) / 765.5 * 100; math.Abs(r.ChangePct-want) > 1e-9 || r.SessionsUsed != trendSessions-2 || r.LatestClose != 764 {
+		t.Errorf("change %.4f%% over %d sessions, latest %v; want %.4f%% over %d, latest 764", r.ChangePct, r.SessionsUsed, r.LatestClose, want, trendSessions-2)
+	}
+	if strings.Contains(r.Analysis, "Inf") || strings.Contains(r.Analysis, "NaN") {
+		t.Errorf("analysis has Inf/NaN:\n%s", r.Analysis)
+	}
+	if _, err := json.Marshal(r); err != nil {
+		t.Errorf("json.Marshal: %v", err)
+	}
+	// 騰落率も指標も、売買なしの日をデータから除いた場合と同じになる
+	removed := jquantstest.DefaultFixtures()
+	removed.DailyQuotes = slices.DeleteFunc(removed.DailyQuotes, func(q jquants.DailyQuote) bool {
+		return q.Code == "39990" && (q.Date == "2025-06-12" || q.Date == "2025-07-10")
+	})
+	want, err := (&PriceTrendTool{Data: removed, Calendar: calendar.New(removed.TradingCalendar), Indicators: AllIndicators}).Trend(context.Background(), "39990", "2025-07-10")
+	if err != nil {
+		t.Fatalf("Trend without the no-trade days: %v", err)
+	}
+	if r != want {
+		t.Errorf("got\n%+v\nwant the same as without the no-trade days\n%+v", r, want)
+	}
+
+	// 期間中ずっと売買なしなら騰落率は出さない
+	fx = jquantstest.DefaultFixtures()
+	var dates []string
+	for _, q := range fx.DailyQuotes {
+		if q.Code == "39990" && q.Date >= "2025-06-12" && q.Date <= "2025-07-10" {
+			dates = append(dates, q.Date)
+		}
+	}
+	noTrade(fx, "39990", dates...)
+	r, err = (&PriceTrendTool{Data: fx, Calendar: calendar.New(fx.TradingCalendar)}).Trend(context.Background(), "39990", "2025-07-10")
+	if err != nil {
+		t.Fatalf("Trend: %v", err)
+	}
+	if r.Trend != "" || r.ChangePct != 0 {
+		t.Errorf("got %+v, want no trend without trades", r)
+	}
+	if _, err := json.Marshal(r); err != nil {
+		t.Errorf("json.Marshal: %v", err)
+	}
+}
+
+// 売買が成立しなかった日 (四本値・出来高が 0) は指標の系列に入れず、その日がない場合と同じ値になる
+func TestFormatIndicatorsSkipsNoTradeDays(t *testing.T) {
+	fx := jquantstest.DefaultFixtures()
+	var quotes []jquants.DailyQuote
+	for _, q := range fx.DailyQuotes {
+		if q.Code == "39990" && q.Date <= "2025-07-10" {
+			quotes = append(quotes, q)
+		}
+	}
+	quotes = jquants.ApplyPriceMode(quotes, jquants.PriceAdjusted)
+	if len(quotes) < 20 {
+		t.Fatalf("only %d quotes in fixtures", len(quotes))
+	}
+	want := formatIndicators(quotes, AllIndicators)
+
+	// 直近10日の中に売買なしの日を挟む
+	i := len(quotes) - 5
+	withGap := slices.Insert(slices.Clone(quotes), i, jquants.DailyQuote{Date: quotes[i-1].Date, Code: "39990", AdjustmentFactor: 1})
+	if got := formatIndicators(withGap, AllIndicators); got != want {
+		t.Errorf("with a no-trade day:\n%s\nwant:\n%s", got, want)
+	}
+	// 最終日に売買がなくても直前の終値で計算する
+	withLast := append(slices.Clone(quotes), jquants.DailyQuote{Date: "2025-07-11", Code: "39990", AdjustmentFactor: 1})
+	if got := formatIndicators(withLast, AllIndicators); got != want {
+		t.Errorf("with no trade on the last day:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// base_date に売買がない同業他社は、直前の終値で騰落率・PER・PBR を計算する (-100% にしない)
+func TestPeerMetricsNoTradeOnBaseDate(t *testing.T) {
+	base := time.Date(2025, 7, 10, 0, 0, 0, 0, time.UTC)
+	run := func(fx *jquantstest.Fixtures) peerMetrics {
+		t.Helper()
+		tool := &SectorPeerTool{Data: fx, Calendar: calendar.New(fx.TradingCalendar)}
+		from, err := tool.Calendar.SessionsBack(base, trendSessions)
+		if err != nil {
+			t.Fatalf("SessionsBack: %v", err)
+		}
+		m, err := tool.metrics(context.Background(), fx, jquants.ListedInfo{Code: "39990"}, from, base)
+		if err != nil {
+			t.Fatalf("metrics: %v", err)
+		}
+		return m
+	}
+
+	noTrade := jquantstest.DefaultFixtures()
+	for i, q := range noTrade.DailyQuotes {
+		if q.Code == "39990" && q.Date == "2025-07-10" {
+			noTrade.DailyQuotes[i] = jquants.DailyQuote{Date: q.Date, Code: q.Code, AdjustmentFactor: 1}
+		}
+	}
+	removed := jquantstest.DefaultFixtures()
+	removed.DailyQuotes = slices.DeleteFunc(removed.DailyQuotes, func(q jquants.DailyQuote) bool {
+		return q.Code == "39990" && q.Date == "2025-07-10"
+	})
+
+	got, want := run(noTrade), run(removed)
+	if got.Return == nil || want.Return == nil || *got.Return != *want.Return {
+		t.Errorf("Return = %v, want %v", ptrValue(got.Return), ptrValue(want.Return))
+	}
+	if ptrValue(got.PER) != ptrValue(want.PER) || ptrValue(got.PBR) != ptrValue(want.PBR) {
+		t.Errorf("PER/PBR = %v/%v, want %v/%v", ptrValue(got.PER), ptrValue(got.PBR), ptrValue(want.PER), ptrValue(want.PBR))
+	}
+}
+
+func ptrValue(v *float64) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+// 日足の取得回数を数え、常に err を返す取得元
+type failingQuotes struct {
+	*jquantstest.Fixtures
+	err   error
+	calls int
+}
+
+func (f *failingQuotes) GetDailyQuotesWithMode(ctx context.Context, code string, fromDate string, toDate string, mode jquants.PriceMode) ([]jquants.DailyQuote, error) {
+	f.calls++
+	return nil, f.err
+}
+
+// 認証エラーやキャンセルでは指標用の長い期間を短い期間で取り直さない
+func TestPriceTrendToolNoRefetchOnFatalError(t *testing.T) {
+	fx := jquantstest.DefaultFixtures()
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		err       error
+		wantCalls int
+	}{
+		{"unauthorized", context.Background(), jquants.ErrUnauthorized, 1},
+		{"canceled", canceled, context.Canceled, 1},
+		// 同期期間の短いローカルデータストアなど
+		{"other error", context.Background(), errors.New("not synced"), 2},
+	}
+	for _, tt := range tests {
+		md := &failingQuotes{Fixtures: fx, err: tt.err}
+		tool := &PriceTrendTool{Data: md, Calendar: calendar.New(fx.TradingCalendar), Indicators: AllIndicators}
+		_, err := tool.Trend(tt.ctx, "39990", "2025-07-10")
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+		}
+		if md.calls != tt.wantCalls {
+			t.Errorf("%s: fetched quotes %d times, want %d", tt.name, md.calls, tt.wantCalls)
+		}
+	}
+}
+
+// prices_am は今日の前場だけなので、過去の base_date には使わない
+func TestMorningSessionToolPricesAMOnlyToday(t *testing.T) {
+	fx := jquantstest.DefaultFixtures()
+	// 2025-07-31 の前場は日足にまだなく prices_am にだけある状態
+	fx.DailyQuotes = slices.DeleteFunc(fx.DailyQuotes, func(q jquants.DailyQuote) bool { return q.Date == "2025-07-31" })
+	tool := &MorningSessionTool{Data: fx, Calendar: calendar.New(fx.TradingCalendar)}
+	defer func(orig func() time.Time) { timeNow = orig }(timeNow)
+
+	tests := []struct {
+		name   string
+		now    time.Time
+		wantAM bool
+	}{
+		{"on the day", time.Date(2025, 7, 31, 12, 0, 0, 0, calendar.JST), true},
+		{"later date", time.Date(2025, 8, 1, 12, 0, 0, 0, calendar.JST), false},
+	}
+	for _, tt := range tests {
+		timeNow = func() time.Time { return tt.now }
+		got, err := tool.morningSessionLogic(context.Background(), "72030", "2025-07-31")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if hasAM := strings.Contains(got, "Open 2693"); hasAM != tt.wantAM {
+			t.Errorf("%s: got %q, want morning session %v", tt.name, got, tt.wantAM)
+		}
+	}
+}
+
+// カレンダーなしでも暦日で近似して空売り比率を出す (パニックしない)
+func TestSupplyDemandToolWithoutCalendar(t *testing.T) {
+	fx := jquantstest.DefaultFixtures()
+	base := time.Date(2025, 7, 18, 0, 0, 0, 0, time.UTC)
+	withCal, err := (&SupplyDemandTool{Data: fx, Calendar: calendar.New(fx.TradingCalendar)}).shortSellingSummary(context.Background(), fx, "72030", base)
+	if err != nil {
+		t.Fatalf("with calendar: %v", err)
+	}
+	got, err := (&SupplyDemandTool{Data: fx}).shortSellingSummary(context.Background(), fx, "72030", base)
+	if err != nil {
+		t.Fatalf("without calendar: %v", err)
+	}
+	if strings.Contains(got, "N/A") != strings.Contains(withCal, "N/A") {
+		t.Errorf("without calendar: %q, with calendar: %q", got, withCal)
+	}
+}
+
+// 4桁の銘柄コードでも5桁コードと同じ銘柄として同業他社を比べる
+func TestSectorPeerToolFourDigitTicker(t *testing.T) {
+	fx := jquantstest.DefaultFixtures()
+	tool := &SectorPeerTool{Data: fx, Calendar: calendar.New(fx.TradingCalendar)}
+	want, err := tool.compare(context.Background(), SectorPeerArgs{Ticker: "72030", BaseDate: "2025-07-10"})
+	if err != nil {
+		t.Fatalf("5-digit ticker: %v", err)
+	}
+	got, err := tool.compare(context.Background(), SectorPeerArgs{Ticker: "7203", BaseDate: "2025-07-10"})
+	if err != nil {
+		t.Fatalf("4-digit ticker: %v", err)
+	}
+	if strings.Contains(want.Analysis, "not found") {
+		t.Fatalf("5-digit ticker: %q", want.Analysis)
+	}
+	if got.Analysis != want.Analysis {
+		t.Errorf("4-digit ticker: %q, want %q", got.Analysis, want.Analysis)
+	}
+}
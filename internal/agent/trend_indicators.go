@@ -0,0 +1,185 @@
+package agent
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/indicators"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+)
+
+// get_price_trend に付けられるテクニカル指標
+const (
+	IndicatorSMA       = "sma"       // 移動平均 (5/25/75日) と終値の乖離率
+	IndicatorEMA       = "ema"       // 20日指数移動平均
+	IndicatorRSI       = "rsi"       // RSI (14日)
+	IndicatorMACD      = "macd"      // MACD (12, 26, 9)
+	IndicatorATR       = "atr"       // ATR (14日)
+	IndicatorBollinger = "bollinger" // ボリンジャーバンド (20日, ±2σ)
+	IndicatorVolume    = "volume"    // 出来高Zスコア (直前20日比)
+	Indicator52Week    = "52w"       // 52週高値・安値からの距離
+)
+
+var AllIndicators = []string{
+	IndicatorSMA, IndicatorEMA, IndicatorRSI, IndicatorMACD,
+	IndicatorATR, IndicatorBollinger, IndicatorVolume, Indicator52Week,
+}
+
+// 指標ごとに遡る営業日数 (EMA を使うものは初期値の影響が薄れるよう長めに取る)
+var indicatorSessions = map[string]int{
+	IndicatorSMA:       75,
+	IndicatorEMA:       60,
+	IndicatorRSI:       60,
+	IndicatorMACD:      100,
+	IndicatorATR:       30,
+	IndicatorBollinger: 20,
+	IndicatorVolume:    21,
+	Indicator52Week:    weekly52Sessions,
+}
+
+// 52週 ≒ 245営業日
+const weekly52Sessions = 245
+
+// "rsi,macd" のようなカンマ区切りの指標名を検証する。"all" ですべて、"none" や空なら付けない
+func ParseIndicators(s string) ([]string, error) {
+	var names []string
+	for _, v := range strings.Split(s, ",") {
+		v = strings.ToLower(strings.TrimSpace(v))
+		switch {
+		case v == "" || v == "none":
+		case v == "all":
+			names = append(names, AllIndicators...)
+		case slices.Contains(AllIndicators, v):
+			names = append(names, v)
+		default:
+			return nil, fmt.Errorf("unknown indicator %q (want %s, all or none)", v, strings.Join(AllIndicators, ", "))
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names), nil
+}
+
+// 指定の指標を計算するのに遡る営業日数
+func indicatorLookback(names []string) int {
+	n := 0
+	for _, name := range names {
+		n = max(n, indicatorSessions[name])
+	}
+	return n
+}
+
+// 指標を AllIndicators の順に1行ずつ書き出す。履歴が足りない指標は N/A
+// 売買が成立しなかった日 (四本値が 0) は系列に入れない
+func formatIndicators(quotes []jquants.DailyQuote, names []string) string {
+	quotes = tradedQuotes(quotes)
+	if len(names) == 0 || len(quotes) == 0 {
+		return ""
+	}
+	closes := make([]float64, len(quotes))
+	highs := make([]float64, len(quotes))
+	lows := make([]float64, len(quotes))
+	volumes := make([]float64, len(quotes))
+	for i, q := range quotes {
+		closes[i], highs[i], lows[i], volumes[i] = q.Close, q.High, q.Low, q.Volume
+	}
+	last := closes[len(closes)-1]
+	vsClose := func(v float64) string {
+		if v <= 0 {
+			return "N/A"
+		}
+		return fmt.Sprintf("%+.1f%%", (last-v)/v*100)
+	}
+
+	var sb strings.Builder
+	line := func(format string, args ...any) {
+		fmt.Fprintf(&sb, "\n"+format, args...)
+	}
+	for _, name := range AllIndicators {
+		if !slices.Contains(names, name) {
+			continue
+		}
+		switch name {
+		case IndicatorSMA:
+			var parts []string
+			for _, period := range []int{5, 25, 75} {
+				if v, ok := indicators.Last(indicators.SMA(closes, period)); ok {
+					parts = append(parts, fmt.Sprintf("%dd %.0f (%s)", period, v, vsClose(v)))
+				} else {
+					parts = append(parts, fmt.Sprintf("%dd N/A", period))
+				}
+			}
+			line("SMA (Close vs MA): %s", strings.Join(parts, " / "))
+		case IndicatorEMA:
+			if v, ok := indicators.Last(indicators.EMA(closes, 20)); ok {
+				line("EMA(20): %.0f (Close vs EMA: %s)", v, vsClose(v))
+			} else {
+				line("EMA(20): N/A (insufficient history)")
+			}
+		case IndicatorRSI:
+			if v, ok := indicators.Last(indicators.RSI(closes, 14)); ok {
+				line("RSI(14): %.1f", v)
+			} else {
+				line("RSI(14): N/A (insufficient history)")
+			}
+		case IndicatorMACD:
+			m := indicators.MACD(closes, 12, 26, 9)
+			macd, ok1 := indicators.Last(m.MACD)
+			signal, ok2 := indicators.Last(m.Signal)
+			if ok1 && ok2 {
+				line("MACD(12,26,9): %.2f / Signal %.2f / Histogram %+.2f", macd, signal, macd-signal)
+			} else {
+				line("MACD(12,26,9): N/A (insufficient history)")
+			}
+		case IndicatorATR:
+			if v, ok := indicators.Last(indicators.ATR(highs, lows, closes, 14)); ok {
+				line("ATR(14): %.1f (%.2f%% of close)", v, v/last*100)
+			} else {
+				line("ATR(14): N/A (insufficient history)")
+			}
+		case IndicatorBollinger:
+			b := indicators.Bollinger(closes, 20, 2)
+			upper, ok1 := indicators.Last(b.Upper)
+			middle, ok2 := indicators.Last(b.Middle)
+			lower, ok3 := indicators.Last(b.Lower)
+			switch {
+			case !ok1 || !ok2 || !ok3:
+				line("Bollinger(20,2σ): N/A (insufficient history)")
+			case upper == lower || middle <= 0:
+				line("Bollinger(20,2σ): N/A (no price movement)")
+			default:
+				line("Bollinger(20,2σ): Upper %.0f / Middle %.0f / Lower %.0f (%%B %.2f, Bandwidth %.1f%%)",
+					upper, middle, lower, (last-lower)/(upper-lower), (upper-lower)/middle*100)
+			}
+		case IndicatorVolume:
+			if v, ok := indicators.Last(indicators.VolumeZScore(volumes, 20)); ok {
+				line("Volume Z-Score (vs prior 20d): %+.2f", v)
+			} else {
+				line("Volume Z-Score (vs prior 20d): N/A (insufficient history)")
+			}
+		case Indicator52Week:
+			d, ok := indicators.DistanceFromHighLow(highs, lows, closes, weekly52Sessions)
+			if !ok {
+				line("52-Week High/Low: N/A")
+				continue
+			}
+			line("52-Week High/Low: %.0f / %.0f (Close %+.1f%% from high, %+.1f%% from low)", d.High, d.Low, d.FromHigh, d.FromLow)
+			if d.Sessions < weekly52Sessions {
+				// 上場から1年経っていない・データが足りない場合はある分だけで計算している
+				fmt.Fprintf(&sb, " [only %d sessions available]", d.Sessions)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// 終値のある (売買が成立した) 日だけ
+func tradedQuotes(quotes []jquants.DailyQuote) []jquants.DailyQuote {
+	out := make([]jquants.DailyQuote, 0, len(quotes))
+	for _, q := range quotes {
+		if q.Close > 0 {
+			out = append(out, q)
+		}
+	}
+	return out
+}
@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/marketdata"
+)
+
+// 株価と決算を組み合わせたバリュエーション指標の計算 (セクター比較・バリュエーションツールで共用)
+
+// history (開示順) から最新の決算期の予想値 (通期決算の後なら来期予想) と、それを載せた開示
+// field は開示の (今期予想, 来期予想) の欄を返す。決算短信の後に業績予想の修正が出ていればそちらを使う
+func latestForecastOf(history []*jquants.ParsedStatement, field func(*jquants.ParsedStatement) (cur, next *float64)) (*float64, *jquants.ParsedStatement) {
+	i := latestReport(history)
+	if i < 0 {
+		return nil, nil
+	}
+	target := history[i].CurrentFiscalYearEndDate
+	if history[i].TypeOfCurrentPeriod == "FY" {
+		target = history[i].NextFiscalYearEndDate
+	}
+	for j := len(history) - 1; j >= i; j-- {
+		s := history[j]
+		cur, next := field(s)
+		switch {
+		case sameMonth(s.CurrentFiscalYearEndDate, target) && cur != nil:
+			return cur, s
+		case sameMonth(s.NextFiscalYearEndDate, target) && next != nil:
+			return next, s
+		}
+	}
+	return nil, nil
+}
+
+// 予想EPS
+func forwardEPS(history []*jquants.ParsedStatement) (*float64, *jquants.ParsedStatement) {
+	return latestForecastOf(history, func(s *jquants.ParsedStatement) (*float64, *float64) {
+		return s.ForecastEarningsPerShare, s.NextYearForecastEarningsPerShare
+	})
+}
+
+// history (開示順) のうち最後に載った1株当たり純資産と、それを載せた開示
+func latestBookValue(history []*jquants.ParsedStatement) (*float64, *jquants.ParsedStatement) {
+	return findLastValue(history, func(s *jquants.ParsedStatement) *float64 { return s.BookValuePerShare })
+}
+
+// history (開示順) のうち最後に field の値が載った開示
+func findLastValue(history []*jquants.ParsedStatement, field func(*jquants.ParsedStatement) *float64) (*float64, *jquants.ParsedStatement) {
+	for i := len(history) - 1; i >= 0; i-- {
+		if v := field(history[i]); v != nil {
+			return v, history[i]
+		}
+	}
+	return nil, nil
+}
+
+// 開示 from に載った1株当たりの値を、その後の株式分割・併合を反映して quotes (生値, 日付昇順) の最終日の株数ベースに揃える
+// 開示より後の AdjustmentFactor (2分割なら 0.5) を掛ける。quotes が開示日まで遡っていなければ、その間の分割は反映されない
+func perShareAsOf(v *float64, from *jquants.ParsedStatement, quotes []jquants.DailyQuote) *float64 {
+	if v == nil || from == nil {
+		return v
+	}
+	adjusted := *v * splitFactorSince(quotes, from.DisclosedDate)
+	return &adjusted
+}
+
+// quotes (日付昇順) のうち since より後の日の調整係数の積
+func splitFactorSince(quotes []jquants.DailyQuote, since time.Time) float64 {
+	d := since.Format("2006-01-02")
+	f := 1.0
+	for _, q := range quotes {
+		if q.Date > d && q.AdjustmentFactor != 0 {
+			f *= q.AdjustmentFactor
+		}
+	}
+	return f
+}
+
+// 終値のある最後の日までの日足 (その後の売買が成立しなかった日を除く)。どの日も売買がなければ空
+func untilLastTrade(quotes []jquants.DailyQuote) []jquants.DailyQuote {
+	last := len(quotes) - 1
+	for last >= 0 && quotes[last].Close <= 0 {
+		last--
+	}
+	return quotes[:last+1]
+}
+
+// 株価 / 1株当たりの値 (PER・PBR)。赤字・債務超過などで値が正でなければ計算しない
+func multiple(price float64, perShare *float64) (float64, bool) {
+	if perShare == nil || *perShare <= 0 || price <= 0 {
+		return 0, false
+	}
+	return price / *perShare, true
+}
+
+// 1株当たりの値を引く開示のうち最も古い開示日 (分割を確認するために株価を遡る日)。どれもなければ ok=false
+func earliestDisclosure(sources ...*jquants.ParsedStatement) (time.Time, bool) {
+	var earliest time.Time
+	for _, s := range sources {
+		if s != nil && (earliest.IsZero() || s.DisclosedDate.Before(earliest)) {
+			earliest = s.DisclosedDate
+		}
+	}
+	return earliest, !earliest.IsZero()
+}
+
+// since (決算の開示日) から baseDate までの生値の日足。since が recent より新しければ recent から取る
+// 同期期間の短いローカルデータストアなどで since から取れない場合は recent からだけ取り直す (その間の分割は反映されない)
+func rawQuotesSince(ctx context.Context, md marketdata.MarketData, code string, since, recent, baseDate time.Time) ([]jquants.DailyQuote, error) {
+	from := recent
+	if !since.IsZero() && since.Before(recent) {
+		from = since
+	}
+	toDate := baseDate.Format("2006-01-02")
+	quotes, err := md.GetDailyQuotesWithMode(ctx, code, from.Format("2006-01-02"), toDate, jquants.PriceRaw)
+	if err != nil && from.Before(recent) && ctx.Err() == nil && !errors.Is(err, jquants.ErrNotFound) && !errors.Is(err, jquants.ErrBadRequest) {
+		quotes, err = md.GetDailyQuotesWithMode(ctx, code, recent.Format("2006-01-02"), toDate, jquants.PriceRaw)
+	}
+	return quotes, err
+}
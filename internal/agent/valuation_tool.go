@@ -0,0 +1,213 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/marketdata"
+	"google.golang.org/adk/tool"
+)
+
+// -------------------------------------------------------
+// バリュエーション (PER・PBR・配当利回り・時価総額) ツール
+// -------------------------------------------------------
+type ValuationArgs struct {
+	Ticker   string `json:"ticker" jsonschema:"The stock ticker symbol (e.g., '72030')."`
+	BaseDate string `json:"base_date" jsonschema:"The reference date for analysis (YYYY-MM-DD)."`
+}
+
+type ValuationResult struct {
+	Analysis string `json:"analysis"`
+}
+
+type ValuationTool struct {
+	Data marketdata.MarketData // 決算の履歴には marketdata.StatementHistoryData、EV の有利子負債には marketdata.DividendData も必要
+}
+
+// 株価を取る期間 (連休を挟んでも base_date 直前の終値が取れるように)
+const valuationPriceDays = 14
+
+func (t *ValuationTool) Execute(ctx tool.Context, args ValuationArgs) (ValuationResult, error) {
+	baseDate, err := time.Parse("2006-01-02", args.BaseDate)
+	if err != nil {
+		return ValuationResult{}, fmt.Errorf("invalid date format")
+	}
+
+	md, err := marketdata.Extension[marketdata.StatementHistoryData](t.Data)
+	if err != nil {
+		return ValuationResult{Analysis: "Valuation: N/A (not available from this data source)"}, nil
+	}
+	records, err := md.GetStatementsByCode(ctx, args.Ticker)
+	if errors.Is(err, jquants.ErrNotFound) || errors.Is(err, jquants.ErrBadRequest) {
+		return ValuationResult{Analysis: fmt.Sprintf("Valuation: N/A (no statements for %s)", args.Ticker)}, nil
+	}
+	if err != nil {
+		return ValuationResult{}, fmt.Errorf("failed to fetch statements: %w", err)
+	}
+	history := statementsAsOf(records, baseDate)
+	if len(history) == 0 {
+		return ValuationResult{Analysis: fmt.Sprintf("Valuation: N/A (no statements disclosed by %s)", args.BaseDate)}, nil
+	}
+
+	v := valuationInputs(history)
+	since, _ := earliestDisclosure(v.epsFrom, v.bpsFrom, v.dpsFrom, v.sharesFrom, v.lastFY)
+	quotes, err := rawQuotesSince(ctx, t.Data, args.Ticker, since, baseDate.AddDate(0, 0, -valuationPriceDays), baseDate)
+	if errors.Is(err, jquants.ErrNotFound) || errors.Is(err, jquants.ErrBadRequest) {
+		quotes, err = nil, nil
+	}
+	if err != nil {
+		return ValuationResult{}, fmt.Errorf("failed to fetch quotes: %w", err)
+	}
+	// base_date に売買がなければ直前の終値のある日で計算する (その日付を結果に出す)
+	quotes = untilLastTrade(quotes)
+	if len(quotes) == 0 {
+		return ValuationResult{Analysis: fmt.Sprintf("Valuation: N/A (no price data for %s as of %s)", args.Ticker, args.BaseDate)}, nil
+	}
+
+	netDebt := t.netDebt(ctx, args.Ticker, baseDate)
+	return ValuationResult{Analysis: formatValuation(v, quotes, netDebt)}, nil
+}
+
+// 決算から引いたバリュエーションの材料と、それぞれを載せた開示 (分割の反映に使う)
+type valuation struct {
+	eps, bps, dps             *float64 // 予想EPS・直近のBPS・予想年間配当
+	epsFrom, bpsFrom, dpsFrom *jquants.ParsedStatement
+	forecastOP                *float64 // 予想営業利益 (EV 倍率用)
+	shares                    *float64 // 期末発行済株式数 - 自己株式数
+	sharesFrom                *jquants.ParsedStatement
+	cash                      *float64                 // 現金及び現金同等物 (財務諸表の詳細がない場合の EV の近似用)
+	lastFY                    *jquants.ParsedStatement // 直近の通期決算 (実績EPS・実績配当)
+}
+
+func valuationInputs(history []*jquants.ParsedStatement) valuation {
+	var v valuation
+	v.eps, v.epsFrom = forwardEPS(history)
+	v.bps, v.bpsFrom = latestBookValue(history)
+	v.dps, v.dpsFrom = latestForecastOf(history, func(s *jquants.ParsedStatement) (*float64, *float64) {
+		return s.ForecastDividendPerShareAnnual, s.NextYearForecastDividendPerShareAnnual
+	})
+	v.forecastOP, _ = latestForecastOf(history, func(s *jquants.ParsedStatement) (*float64, *float64) {
+		return s.ForecastOperatingProfit, s.NextYearForecastOperatingProfit
+	})
+	issued, from := findLastValue(history, func(s *jquants.ParsedStatement) *float64 {
+		return s.NumberOfIssuedAndOutstandingSharesAtTheEndOfFiscalYearIncludingTreasuryStock
+	})
+	if issued != nil {
+		shares := *issued
+		if from.NumberOfTreasuryStockAtTheEndOfFiscalYear != nil {
+			shares -= *from.NumberOfTreasuryStockAtTheEndOfFiscalYear
+		}
+		v.shares, v.sharesFrom = &shares, from
+	}
+	v.cash, _ = findLastValue(history, func(s *jquants.ParsedStatement) *float64 { return s.CashAndEquivalents })
+	v.lastFY = findLast(history, func(s *jquants.ParsedStatement) bool {
+		return isFinancialStatement(s) && s.TypeOfCurrentPeriod == "FY"
+	})
+	return v
+}
+
+// baseDate 時点で最新の財務諸表の有利子負債 - 現金。財務諸表の詳細が取れなければ nil
+func (t *ValuationTool) netDebt(ctx context.Context, ticker string, baseDate time.Time) *float64 {
+	md, err := marketdata.Extension[marketdata.DividendData](t.Data)
+	if err != nil {
+		return nil
+	}
+	details, err := md.GetFSDetails(ctx, ticker)
+	if err != nil {
+		return nil
+	}
+	latest := latestFSDetails(details, baseDate)
+	if latest == nil {
+		return nil
+	}
+	cash, debt := latest.Value(cashKeys...), sumDebt(*latest)
+	if cash == nil || debt == nil {
+		return nil
+	}
+	net := *debt - *cash
+	return &net
+}
+
+// 1株当たりの値はすべて quotes の最終日の株数ベース (開示後の分割を反映) に揃えて計算する
+func formatValuation(v valuation, quotes []jquants.DailyQuote, netDebt *float64) string {
+	last := quotes[len(quotes)-1]
+	price := last.Close
+	eps := perShareAsOf(v.eps, v.epsFrom, quotes)
+	bps := perShareAsOf(v.bps, v.bpsFrom, quotes)
+	dps := perShareAsOf(v.dps, v.dpsFrom, quotes)
+
+	lines := []string{fmt.Sprintf("Valuation (Close %.1f on %s):", price, last.Date)}
+
+	// 予想PER と、直近の通期実績からの EPS 成長率で割った PEG
+	per, ok := multiple(price, eps)
+	switch {
+	case ok:
+		lines = append(lines, fmt.Sprintf("- Forward PER: %.1fx (forecast EPS %.2f, %s)", per, *eps, sourceLabel(v.epsFrom)))
+	case eps != nil:
+		lines = append(lines, fmt.Sprintf("- Forward PER: N/A (forecast EPS %.2f)", *eps))
+	default:
+		lines = append(lines, "- Forward PER: N/A (no EPS forecast)")
+	}
+	var actualEPS, actualDPS *float64
+	if v.lastFY != nil {
+		actualEPS = perShareAsOf(v.lastFY.EarningsPerShare, v.lastFY, quotes)
+		actualDPS = perShareAsOf(v.lastFY.ResultDividendPerShareAnnual, v.lastFY, quotes)
+	}
+	if trailing, ok := multiple(price, actualEPS); ok {
+		lines = append(lines, fmt.Sprintf("- Trailing PER: %.1fx (FY actual EPS %.2f, %s)", trailing, *actualEPS, sourceLabel(v.lastFY)))
+	} else {
+		lines = append(lines, fmt.Sprintf("- Trailing PER: N/A (FY actual EPS %s)", fmtNumber(actualEPS)))
+	}
+	if g, ok2 := growthPct(eps, actualEPS); ok && ok2 && g > 0 {
+		lines = append(lines, fmt.Sprintf("- PEG: %.2f (forward PER / forecast EPS growth %+.1f%%)", per/g, g))
+	} else {
+		lines = append(lines, "- PEG: N/A (no forecast EPS growth)")
+	}
+
+	if pbr, ok := multiple(price, bps); ok {
+		lines = append(lines, fmt.Sprintf("- PBR: %.2fx (BPS %.2f, %s)", pbr, *bps, sourceLabel(v.bpsFrom)))
+	} else {
+		lines = append(lines, fmt.Sprintf("- PBR: N/A (BPS %s)", fmtNumber(bps)))
+	}
+
+	yield := func(d *float64) string {
+		if d == nil {
+			return "N/A"
+		}
+		return fmt.Sprintf("%.2f%% (DPS %.2f)", *d/price*100, *d)
+	}
+	lines = append(lines, fmt.Sprintf("- Dividend Yield: forecast %s / last FY actual %s", yield(dps), yield(actualDPS)))
+
+	if v.shares == nil {
+		lines = append(lines, "- Market Cap: N/A (no share count)")
+		return strings.Join(lines, "\n")
+	}
+	shares := *v.shares / splitFactorSince(quotes, v.sharesFrom.DisclosedDate)
+	marketCap := price * shares
+	lines = append(lines, fmt.Sprintf("- Market Cap: %s JPY (%.1fM shares excl. treasury, %s)", fmtMillions(&marketCap), shares/1e6, sourceLabel(v.sharesFrom)))
+
+	// EV は有利子負債 - 現金を足した近似。財務諸表の詳細がなければ現金だけ引く
+	var ev float64
+	var label string
+	switch {
+	case netDebt != nil:
+		ev, label = marketCap+*netDebt, "Market Cap + Debt - Cash"
+	case v.cash != nil:
+		ev, label = marketCap-*v.cash, "Market Cap - Cash, debt unknown"
+	default:
+		return strings.Join(lines, "\n")
+	}
+	line := fmt.Sprintf("- EV (%s): %s JPY", label, fmtMillions(&ev))
+	if m, ok := multiple(ev, v.forecastOP); ok {
+		line += fmt.Sprintf(" | EV / Forecast OpProfit: %.1fx", m)
+	}
+	return strings.Join(append(lines, line), "\n")
+}
+
+func sourceLabel(s *jquants.ParsedStatement) string {
+	return fmt.Sprintf("%s disclosed %s", documentLabel(s), s.DisclosedDate.Format("2006-01-02"))
+}
@@ -0,0 +1,169 @@
+// 個別銘柄の値動きを TOPIX・業種別指数と比較する (相対力)
+package benchmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/calendar"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/marketdata"
+)
+
+// 期間内の株価・指数が足りず比較できない (売買停止・上場直後・指数の未配信など)
+// 呼び出し側はエラーではなく N/A として扱う
+var ErrInsufficientData = errors.New("insufficient data for benchmark")
+
+type Benchmark struct {
+	Data     marketdata.MarketData // TOPIX・業種別指数には marketdata.IndexData も必要
+	Calendar *calendar.Calendar    // nil の場合は暦日で近似する
+	Mode     jquants.PriceMode     // 個別銘柄の価格系列。空なら分割調整済み
+}
+
+// baseDate までの一定期間の騰落率を市場・業種と比べた結果 (騰落率は %)
+type RelativeStrength struct {
+	Ticker   string
+	FromDate string
+	ToDate   string
+	Sessions int
+
+	StockChangePct float64
+	TopixChangePct float64
+	VsTopix        float64 // 株価騰落率 - TOPIX騰落率 (%ポイント)
+
+	SectorName      string
+	SectorIndexCode string   // 業種別指数がない銘柄 (ETF 等) は空
+	SectorChangePct *float64 // 業種別指数が取れない場合は nil
+	VsSector        *float64
+}
+
+// baseDate 以前の直近営業日から sessions 営業日前までの騰落率を比較する
+func (b *Benchmark) RelativeStrength(ctx context.Context, ticker string, baseDate time.Time, sessions int) (*RelativeStrength, error) {
+	from, to := baseDate.AddDate(0, 0, -sessions*7/5), baseDate
+	if b.Calendar != nil {
+		var err error
+		if from, err = b.Calendar.SessionsBack(baseDate, sessions); err != nil {
+			return nil, err
+		}
+		if to, err = b.Calendar.SessionsBack(baseDate, 0); err != nil {
+			return nil, err
+		}
+	}
+	fromDate, toDate := from.Format("2006-01-02"), to.Format("2006-01-02")
+
+	mode := b.Mode
+	if mode == "" {
+		mode = jquants.PriceAdjusted
+	}
+	quotes, err := b.Data.GetDailyQuotesWithMode(ctx, ticker, fromDate, toDate, mode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch quotes: %w", err)
+	}
+	// 売買が成立しなかった日は終値が 0 (null) なので除く
+	quotes = traded(quotes, func(q jquants.DailyQuote) float64 { return q.Close })
+	if len(quotes) < 2 {
+		return nil, fmt.Errorf("%w: price of %s (%s - %s)", ErrInsufficientData, ticker, fromDate, toDate)
+	}
+	// 売買停止などで期間の端にデータがない場合もあるので、実際に終値のある日付で指数と揃える
+	first, last := quotes[0], quotes[len(quotes)-1]
+
+	indices, err := marketdata.Extension[marketdata.IndexData](b.Data)
+	if err != nil {
+		return nil, err
+	}
+	topix, err := indices.GetTopix(ctx, first.Date, last.Date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch TOPIX: %w", err)
+	}
+	topixChange, ok := indexChange(topix)
+	if !ok {
+		return nil, fmt.Errorf("%w: TOPIX (%s - %s)", ErrInsufficientData, first.Date, last.Date)
+	}
+
+	rs := &RelativeStrength{
+		Ticker:         ticker,
+		FromDate:       first.Date,
+		ToDate:         last.Date,
+		Sessions:       len(quotes) - 1,
+		StockChangePct: (last.Close - first.Close) / first.Close * 100,
+		TopixChangePct: topixChange,
+	}
+	rs.VsTopix = rs.StockChangePct - rs.TopixChangePct
+
+	// 業種別指数は取れなくても TOPIX との比較だけは返す
+	info, err := b.Data.GetCompanyInfo(ctx, ticker)
+	if err != nil {
+		return rs, nil
+	}
+	rs.SectorName = info.Sector33CodeName
+	indexCode, ok := jquants.Sector33IndexCode(info.Sector33Code)
+	if !ok {
+		return rs, nil
+	}
+	rs.SectorIndexCode = indexCode
+
+	sector, err := indices.GetIndexQuotes(ctx, indexCode, first.Date, last.Date)
+	if err != nil {
+		return rs, nil
+	}
+	if change, ok := indexChange(sector); ok {
+		vs := rs.StockChangePct - change
+		rs.SectorChangePct = &change
+		rs.VsSector = &vs
+	}
+	return rs, nil
+}
+
+// 指定日の TOPIX の始値→終値の騰落率 (%)。バックテストのベンチマーク用
+func (b *Benchmark) TopixDayChange(ctx context.Context, date time.Time) (float64, error) {
+	indices, err := marketdata.Extension[marketdata.IndexData](b.Data)
+	if err != nil {
+		return 0, err
+	}
+	d := date.Format("2006-01-02")
+	quotes, err := indices.GetTopix(ctx, d, d)
+	if err != nil {
+		return 0, err
+	}
+	if len(quotes) == 0 || quotes[0].Open <= 0 {
+		return 0, fmt.Errorf("%w: no TOPIX on %s", ErrInsufficientData, d)
+	}
+	return (quotes[0].Close - quotes[0].Open) / quotes[0].Open * 100, nil
+}
+
+// 期間の最初と最後の終値から騰落率を計算する
+func indexChange(quotes []jquants.IndexQuote) (float64, bool) {
+	quotes = traded(quotes, func(q jquants.IndexQuote) float64 { return q.Close })
+	if len(quotes) < 2 {
+		return 0, false
+	}
+	first, last := quotes[0], quotes[len(quotes)-1]
+	return (last.Close - first.Close) / first.Close * 100, true
+}
+
+// 終値が正の日だけ
+func traded[T any](quotes []T, close func(T) float64) []T {
+	out := make([]T, 0, len(quotes))
+	for _, q := range quotes {
+		if close(q) > 0 {
+			out = append(out, q)
+		}
+	}
+	return out
+}
+
+// モデル・ログ向けの読みやすい表現
+func (rs *RelativeStrength) String() string {
+	s := fmt.Sprintf(
+		"Period: %s to %s (%d sessions)\nStock Change: %+.2f%%\nTOPIX Change: %+.2f%% (Relative: %+.2fpt)",
+		rs.FromDate, rs.ToDate, rs.Sessions, rs.StockChangePct, rs.TopixChangePct, rs.VsTopix,
+	)
+	if rs.SectorChangePct != nil {
+		s += fmt.Sprintf("\nSector (%s) Change: %+.2f%% (Relative: %+.2fpt)", rs.SectorName, *rs.SectorChangePct, *rs.VsSector)
+	} else {
+		s += "\nSector Change: N/A"
+	}
+	return s
+}
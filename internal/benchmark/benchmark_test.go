@@ -0,0 +1,67 @@
+package benchmark
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/calendar"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquantstest"
+)
+
+func TestInsufficientData(t *testing.T) {
+	fx := jquantstest.DefaultFixtures()
+	b := &Benchmark{Data: fx, Calendar: calendar.New(fx.TradingCalendar)}
+	ctx := context.Background()
+
+	if _, err := b.TopixDayChange(ctx, time.Date(2025, 7, 18, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Errorf("TopixDayChange on a trading day: %v", err)
+	}
+	// 土曜日は TOPIX がない
+	if _, err := b.TopixDayChange(ctx, time.Date(2025, 7, 19, 0, 0, 0, 0, time.UTC)); !errors.Is(err, ErrInsufficientData) {
+		t.Errorf("TopixDayChange on Saturday: err = %v, want ErrInsufficientData", err)
+	}
+
+	if _, err := b.RelativeStrength(ctx, "72030", time.Date(2025, 7, 18, 0, 0, 0, 0, time.UTC), 5); err != nil {
+		t.Errorf("RelativeStrength: %v", err)
+	}
+	// 0営業日では騰落率を計算できない
+	if _, err := b.RelativeStrength(ctx, "72030", time.Date(2025, 7, 18, 0, 0, 0, 0, time.UTC), 0); !errors.Is(err, ErrInsufficientData) {
+		t.Errorf("RelativeStrength over 0 sessions: err = %v, want ErrInsufficientData", err)
+	}
+}
+
+// 期間の最後の営業日に売買がなければ、その前の終値のある日までで比べる
+func TestRelativeStrengthNoTradeOnLastDay(t *testing.T) {
+	fx := jquantstest.DefaultFixtures()
+	for i, q := range fx.DailyQuotes {
+		if q.Code == "72030" && q.Date == "2025-07-18" {
+			fx.DailyQuotes[i] = jquants.DailyQuote{Date: q.Date, Code: q.Code, AdjustmentFactor: 1}
+		}
+	}
+	b := &Benchmark{Data: fx, Calendar: calendar.New(fx.TradingCalendar)}
+	rs, err := b.RelativeStrength(context.Background(), "72030", time.Date(2025, 7, 18, 0, 0, 0, 0, time.UTC), 5)
+	if err != nil {
+		t.Fatalf("RelativeStrength: %v", err)
+	}
+	if rs.ToDate != "2025-07-17" || rs.Sessions != 4 {
+		t.Errorf("period ends %s over %d sessions, want 2025-07-17 over 4", rs.ToDate, rs.Sessions)
+	}
+	if rs.StockChangePct <= -100 {
+		t.Errorf("StockChangePct = %v, want the change to the last traded close", rs.StockChangePct)
+	}
+}
+
+// カレンダーがなければ暦日で近似する (パニックしない)
+func TestRelativeStrengthWithoutCalendar(t *testing.T) {
+	fx := jquantstest.DefaultFixtures()
+	rs, err := (&Benchmark{Data: fx}).RelativeStrength(context.Background(), "72030", time.Date(2025, 7, 18, 0, 0, 0, 0, time.UTC), 5)
+	if err != nil {
+		t.Fatalf("RelativeStrength: %v", err)
+	}
+	if rs.ToDate != "2025-07-18" || rs.Sessions < 1 {
+		t.Errorf("period %s - %s over %d sessions, want up to 2025-07-18", rs.FromDate, rs.ToDate, rs.Sessions)
+	}
+}
@@ -0,0 +1,121 @@
+// J-Quants の取引カレンダーを元にした営業日計算
+package calendar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/marketdata"
+)
+
+const dateLayout = "2006-01-02"
+
+// 読み込んだカレンダーの範囲外の日付を問い合わせた場合のエラー
+var ErrOutOfRange = errors.New("date is out of the loaded trading calendar range")
+
+// 取引カレンダー
+// 日付は "YYYY-MM-DD" の文字列で比較するので、time.Time のタイムゾーンには依存しない
+type Calendar struct {
+	tradingDays []string // 立会日 (昇順)
+	first, last string   // カレンダーが網羅している範囲
+}
+
+func New(days []jquants.TradingCalendarDay) *Calendar {
+	c := &Calendar{}
+	for _, d := range days {
+		if c.first == "" || d.Date < c.first {
+			c.first = d.Date
+		}
+		if d.Date > c.last {
+			c.last = d.Date
+		}
+		if d.IsTradingDay() {
+			c.tradingDays = append(c.tradingDays, d.Date)
+		}
+	}
+	sort.Strings(c.tradingDays)
+	c.tradingDays = slices.Compact(c.tradingDays)
+	return c
+}
+
+// 取得元から全期間のカレンダーを取得する
+func Load(ctx context.Context, md marketdata.MarketData) (*Calendar, error) {
+	days, err := md.GetTradingCalendar(ctx, "", "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load trading calendar: %w", err)
+	}
+	return New(days), nil
+}
+
+func (c *Calendar) inRange(d string) bool {
+	return d >= c.first && d <= c.last
+}
+
+func (c *Calendar) IsTradingDay(t time.Time) bool {
+	d := t.Format(dateLayout)
+	_, found := slices.BinarySearch(c.tradingDays, d)
+	return found
+}
+
+// t より後の最初の立会日
+func (c *Calendar) NextTradingDay(t time.Time) (time.Time, error) {
+	d := t.Format(dateLayout)
+	i := sort.SearchStrings(c.tradingDays, d)
+	if i < len(c.tradingDays) && c.tradingDays[i] == d {
+		i++
+	}
+	if !c.inRange(d) || i >= len(c.tradingDays) {
+		return time.Time{}, fmt.Errorf("next trading day after %s: %w", d, ErrOutOfRange)
+	}
+	return parse(c.tradingDays[i]), nil
+}
+
+// t より前の最後の立会日
+func (c *Calendar) PrevTradingDay(t time.Time) (time.Time, error) {
+	d := t.Format(dateLayout)
+	i := sort.SearchStrings(c.tradingDays, d) - 1
+	if !c.inRange(d) || i < 0 {
+		return time.Time{}, fmt.Errorf("previous trading day before %s: %w", d, ErrOutOfRange)
+	}
+	return parse(c.tradingDays[i]), nil
+}
+
+// from から to まで (両端を含む) の立会日
+func (c *Calendar) TradingDaysBetween(from, to time.Time) []time.Time {
+	lo := sort.SearchStrings(c.tradingDays, from.Format(dateLayout))
+	hi := sort.SearchStrings(c.tradingDays, to.Format(dateLayout))
+	if hi < len(c.tradingDays) && c.tradingDays[hi] == to.Format(dateLayout) {
+		hi++
+	}
+
+	var days []time.Time
+	for _, d := range c.tradingDays[lo:max(lo, hi)] {
+		days = append(days, parse(d))
+	}
+	return days
+}
+
+// t 以前の直近の立会日から数えて n 営業日前の立会日を返す
+// (n=0 なら t 以前の直近の立会日。t が立会日なら t 自身)
+func (c *Calendar) SessionsBack(t time.Time, n int) (time.Time, error) {
+	d := t.Format(dateLayout)
+	i := sort.SearchStrings(c.tradingDays, d)
+	if i >= len(c.tradingDays) || c.tradingDays[i] != d {
+		i-- // t が立会日でなければ直前の立会日を起点にする
+	}
+	i -= n
+	if !c.inRange(d) || i < 0 || i >= len(c.tradingDays) {
+		return time.Time{}, fmt.Errorf("%d sessions back from %s: %w", n, d, ErrOutOfRange)
+	}
+	return parse(c.tradingDays[i]), nil
+}
+
+func parse(d string) time.Time {
+	t, _ := time.Parse(dateLayout, d)
+	return t
+}
@@ -0,0 +1,33 @@
+package calendar
+
+import "time"
+
+// 東証の時刻はすべて日本時間
+var JST = time.FixedZone("JST", 9*60*60)
+
+// 東証の立会時間 (JST, "15:04:05" 形式)
+// 2024-11-05 から大引けが 15:00 → 15:30 に延長されている
+const (
+	MorningOpen    = "09:00:00"
+	MorningClose   = "11:30:00"
+	AfternoonOpen  = "12:30:00"
+	AfternoonClose = "15:30:00"
+)
+
+// 開示時刻 (J-Quants の DisclosedTime) が前場中〜昼休み (9:00〜12:30) か
+// この時間帯の開示は前場の値動きと比べて評価でき、同日の後場寄りでエントリーできる
+// 時刻が空・不正な場合は false
+func MiddayDisclosure(disclosedTime string) bool {
+	t, ok := normalizeTime(disclosedTime)
+	return ok && t >= MorningOpen && t < AfternoonOpen
+}
+
+// "15:04:05" / "15:04" を "15:04:05" に揃える
+func normalizeTime(s string) (string, bool) {
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format("15:04:05"), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,14 @@
+//go:build fixtures
+
+package cmdutil
+
+import (
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquantstest"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/marketdata"
+)
+
+// サンプルデータは jquantstest (httptest と fixture JSON) ごとリンクされるので、
+// -tags fixtures でビルドしたときだけ -source fixtures を使えるようにしている
+func openFixtures() (marketdata.MarketData, error) {
+	return jquantstest.DefaultFixtures(), nil
+}
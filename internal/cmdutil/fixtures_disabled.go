@@ -0,0 +1,13 @@
+//go:build !fixtures
+
+package cmdutil
+
+import (
+	"fmt"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/marketdata"
+)
+
+func openFixtures() (marketdata.MarketData, error) {
+	return nil, fmt.Errorf("-source %s is only available in binaries built with -tags fixtures", SourceFixtures)
+}
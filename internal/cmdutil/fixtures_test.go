@@ -0,0 +1,20 @@
+//go:build fixtures
+
+package cmdutil
+
+import (
+	"context"
+	"testing"
+)
+
+// go test -tags fixtures ./internal/cmdutil で、同梱のサンプルデータが認証情報なしで読めることを確かめる
+func TestOpenFixturesWithoutCredentials(t *testing.T) {
+	md, closeData, err := OpenMarketData(emptyConfig(t), SourceFlags{Source: SourceFixtures}, CacheFlags{})
+	if err != nil {
+		t.Fatalf("fixtures: %v", err)
+	}
+	defer closeData()
+	if _, err := md.GetTradingCalendar(context.Background(), "", ""); err != nil {
+		t.Errorf("GetTradingCalendar: %v", err)
+	}
+}
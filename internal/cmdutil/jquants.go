@@ -0,0 +1,57 @@
+// cmd 以下の各コマンドで共通のフラグ・初期化処理
+package cmdutil
+
+import (
+	"errors"
+	"flag"
+	"log"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/config"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+)
+
+// J-Quants レスポンスキャッシュ関連のフラグ
+type CacheFlags struct {
+	NoCache bool
+	Purge   bool
+	Dir     string
+}
+
+// fs にキャッシュ関連のフラグを登録する (Dir が空なら config の値を使う)
+func (f *CacheFlags) Register(fs *flag.FlagSet) {
+	fs.BoolVar(&f.NoCache, "no-cache", false, "J-Quants のレスポンスキャッシュを使わない")
+	fs.BoolVar(&f.Purge, "purge-cache", false, "起動時に J-Quants のレスポンスキャッシュを削除する")
+	fs.StringVar(&f.Dir, "cache-dir", "", "キャッシュの保存先 (デフォルト: $JQUANTS_CACHE_DIR または "+config.DefaultJQuantsCacheDir+")")
+}
+
+// フラグに従ってキャッシュを設定した J-Quants クライアントを作る
+// ローカルデータストアやサンプルデータだけで動かす場合は不要なので、リフレッシュトークンはここで確かめる
+func NewJQuantsClient(cfg *config.Config, cf CacheFlags, opts ...jquants.Option) (*jquants.Client, error) {
+	if cfg.JQuantsRefreshToken == "" {
+		return nil, errors.New("JQUANTS_REFRESH_TOKEN must be set to use the J-Quants API")
+	}
+	dir := cf.Dir
+	if dir == "" {
+		dir = cfg.JQuantsCacheDir
+	}
+
+	if cf.Purge {
+		if err := (&jquants.FileCache{Dir: dir}).Purge(); err != nil {
+			return nil, err
+		}
+		log.Printf("Purged J-Quants cache: %s", dir)
+	}
+
+	if cfg.JQuantsBaseURL != "" {
+		opts = append(opts, jquants.WithBaseURL(cfg.JQuantsBaseURL))
+	}
+
+	if !cf.NoCache {
+		cache, err := jquants.NewFileCache(dir)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, jquants.WithCache(cache))
+	}
+	return jquants.NewClient(cfg.JQuantsRefreshToken, opts...), nil
+}
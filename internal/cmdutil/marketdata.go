@@ -0,0 +1,63 @@
+package cmdutil
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/config"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/marketdata"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/store"
+)
+
+// 市場データの取得元
+const (
+	SourceJQuants  = "jquants"  // J-Quants API (レスポンスキャッシュあり)
+	SourceStore    = "store"    // cmd/sync のローカルデータストア (オフライン)
+	SourceFixtures = "fixtures" // 同梱のサンプルデータ (オフラインデモ用、-tags fixtures でビルドした場合のみ)
+)
+
+// 市場データの取得元を選ぶフラグ
+type SourceFlags struct {
+	Source string
+	Store  string
+}
+
+// fs に取得元関連のフラグを登録する (Store が空なら config の値を使う)
+func (f *SourceFlags) Register(fs *flag.FlagSet) {
+	fs.StringVar(&f.Source, "source", SourceJQuants, "市場データの取得元: jquants (API) / store (cmd/sync のローカルデータストア) / fixtures (同梱のサンプルデータ、要 -tags fixtures)")
+	fs.StringVar(&f.Store, "store", "", "-source store で読む SQLite ファイル (デフォルト: $JQUANTS_STORE_PATH または "+config.DefaultJQuantsStorePath+")")
+}
+
+// フラグに従って市場データの取得元を開く。使い終わったら2つ目の戻り値の関数で閉じること
+// store は決算・株価・上場銘柄情報・カレンダーしか持たないので、信用残や指数などを使うツールは N/A になる
+func OpenMarketData(cfg *config.Config, sf SourceFlags, cf CacheFlags, opts ...jquants.Option) (marketdata.MarketData, func(), error) {
+	switch sf.Source {
+	case SourceJQuants, "":
+		jq, err := NewJQuantsClient(cfg, cf, opts...)
+		if err != nil {
+			return nil, nil, err
+		}
+		return jq, func() {}, nil
+	case SourceStore:
+		path := sf.Store
+		if path == "" {
+			path = cfg.JQuantsStorePath
+		}
+		st, err := store.Open(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		log.Printf("Using local data store: %s", path)
+		return st, func() { st.Close() }, nil
+	case SourceFixtures:
+		md, err := openFixtures()
+		if err != nil {
+			return nil, nil, err
+		}
+		log.Println("Using bundled sample data.")
+		return md, func() {}, nil
+	}
+	return nil, nil, fmt.Errorf("unknown source %q (want %s, %s or %s)", sf.Source, SourceJQuants, SourceStore, SourceFixtures)
+}
@@ -0,0 +1,36 @@
+package cmdutil
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/config"
+)
+
+// 認証情報のない環境 (GOOGLE_API_KEY・JQUANTS_REFRESH_TOKEN とも空)
+func emptyConfig(t *testing.T) *config.Config {
+	t.Helper()
+	for _, key := range []string{"GOOGLE_API_KEY", "JQUANTS_REFRESH_TOKEN", "JQUANTS_BASE_URL", "JQUANTS_CACHE_DIR", "JQUANTS_STORE_PATH"} {
+		t.Setenv(key, "")
+	}
+	return config.Load()
+}
+
+func TestOpenMarketDataWithoutCredentials(t *testing.T) {
+	cfg := emptyConfig(t)
+
+	// ローカルデータストアは認証情報なしで開ける
+	md, closeData, err := OpenMarketData(cfg, SourceFlags{Source: SourceStore, Store: filepath.Join(t.TempDir(), "jquants.db")}, CacheFlags{})
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	closeData()
+	if md == nil {
+		t.Error("store: got nil market data")
+	}
+
+	// API はリフレッシュトークンがなければ開かない
+	if _, _, err := OpenMarketData(cfg, SourceFlags{Source: SourceJQuants}, CacheFlags{NoCache: true}); err == nil {
+		t.Error("jquants without a refresh token: want error")
+	}
+}
@@ -0,0 +1,170 @@
+package cmdutil
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// results.csv の列 (cmd/app が書き、backtest・analysis・ダッシュボードが列名で読む)
+// 列を増やすときは末尾に足すこと。古いファイルは AppendResults が書き直す
+var ResultsHeader = append([]string{
+	"Date", "Ticker", "CompanyName", "Action", "Confidence", "Reasoning",
+	"Financials", "Technicals", "PromptID", "DisclosedTime",
+}, TrendColumns...)
+
+// 株価トレンドツールの数値の列 (results.csv と watchlist.csv の末尾に付く)
+var TrendColumns = []string{
+	"Trend", "ChangePct", "AvgValueJPY", "AvgVolatilityPct", "LatestClose", "SessionsUsed",
+}
+
+// results.csv の1行 (列名 -> 値)。存在しない列は空文字
+type ResultRow map[string]string
+
+// results.csv を読み込む。列は位置ではなくヘッダーの列名で引く
+// 途中で列が増えた古いファイルでも読めるよう、行ごとの列数の違いは許容する
+func ReadResults(path string) ([]ResultRow, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []ResultRow
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, toRow(header, record))
+	}
+}
+
+func toRow(header, record []string) ResultRow {
+	row := make(ResultRow, len(header))
+	for i, col := range header {
+		if i < len(record) {
+			row[col] = record[i]
+		}
+	}
+	return row
+}
+
+// 追記用に開いた results.csv
+type ResultsWriter struct {
+	f *os.File
+	w *csv.Writer
+}
+
+// results.csv を追記用に開く (なければヘッダー付きで作る)
+// 既存ファイルのヘッダーが ResultsHeader と違う場合は、列名で対応付けて新しい列構成に書き直してから開く
+func AppendResults(path string) (*ResultsWriter, error) {
+	if err := migrateResults(path); err != nil {
+		return nil, fmt.Errorf("migrate %s: %w", path, err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	rw := &ResultsWriter{f: f, w: csv.NewWriter(f)}
+
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if stat.Size() == 0 {
+		rw.w.Write(ResultsHeader)
+		rw.w.Flush()
+	}
+	return rw, rw.w.Error()
+}
+
+// row を ResultsHeader の順に書き込んで即座にフラッシュする (途中で落ちても結果が残るように)
+func (rw *ResultsWriter) Write(row ResultRow) error {
+	record := make([]string, len(ResultsHeader))
+	for i, col := range ResultsHeader {
+		record[i] = row[col]
+	}
+	rw.w.Write(record)
+	rw.w.Flush()
+	return rw.w.Error()
+}
+
+func (rw *ResultsWriter) Close() error {
+	rw.w.Flush()
+	return errors.Join(rw.w.Error(), rw.f.Close())
+}
+
+// 古い列構成の results.csv を ResultsHeader の列構成に書き直す
+// 知らない列があるファイルはデータを失わないよう書き直さずにエラーにする
+func migrateResults(path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	f.Close()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 || slices.Equal(records[0], ResultsHeader) {
+		return nil
+	}
+
+	header := records[0]
+	for _, col := range header {
+		if !slices.Contains(ResultsHeader, col) {
+			return fmt.Errorf("unknown column %q (expected %v)", col, ResultsHeader)
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".results-*.csv")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	w := csv.NewWriter(tmp)
+	w.Write(ResultsHeader)
+	for _, record := range records[1:] {
+		row := toRow(header, record)
+		out := make([]string, len(ResultsHeader))
+		for i, col := range ResultsHeader {
+			out[i] = row[col]
+		}
+		w.Write(out)
+	}
+	w.Flush()
+	if err := errors.Join(w.Error(), tmp.Close()); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -7,9 +7,18 @@ import (
 	"github.com/joho/godotenv"
 )
 
+// J-Quants レスポンスキャッシュのデフォルト保存先
+const DefaultJQuantsCacheDir = ".cache/jquants"
+
+// cmd/sync がダウンロードしたデータの保存先のデフォルト
+const DefaultJQuantsStorePath = "data/jquants.db"
+
 type Config struct {
 	GoogleAPIKey        string
 	JQuantsRefreshToken string
+	JQuantsCacheDir     string
+	JQuantsBaseURL      string // 空なら本番の J-Quants API (偽サーバーに向ける時に使う)
+	JQuantsStorePath    string // ローカルデータストア (SQLite) のパス
 }
 
 func Load() *Config {
@@ -21,11 +30,19 @@ func Load() *Config {
 	cfg := &Config{
 		GoogleAPIKey:        os.Getenv("GOOGLE_API_KEY"),
 		JQuantsRefreshToken: os.Getenv("JQUANTS_REFRESH_TOKEN"),
+		JQuantsCacheDir:     os.Getenv("JQUANTS_CACHE_DIR"),
+		JQuantsBaseURL:      os.Getenv("JQUANTS_BASE_URL"),
+		JQuantsStorePath:    os.Getenv("JQUANTS_STORE_PATH"),
 	}
-
-	if cfg.GoogleAPIKey == "" || cfg.JQuantsRefreshToken == "" {
-		log.Fatal("Error: GOOGLE_API_KEY and JQUANTS_REFRESH_TOKEN must be set.")
+	if cfg.JQuantsCacheDir == "" {
+		cfg.JQuantsCacheDir = DefaultJQuantsCacheDir
 	}
+	if cfg.JQuantsStorePath == "" {
+		cfg.JQuantsStorePath = DefaultJQuantsStorePath
+	}
+
+	// 値の有無はここでは確かめない (オフラインの取得元や cmd/sync は認証情報なしで動く)
+	// JQUANTS_REFRESH_TOKEN は cmdutil.NewJQuantsClient、GOOGLE_API_KEY は agent.NewStockAnalyzer が確かめる
 
 	return cfg
-}
\ No newline at end of file
+}
@@ -0,0 +1,237 @@
+// Package indicators はテクニカル指標の計算 (SMA/EMA, RSI, MACD, ATR, ボリンジャーバンド, 出来高Zスコア, 52週高値・安値からの距離)。
+// 入力は古い順の日足の系列。戻り値の系列は入力と同じ長さで、期間が足りず計算できない位置は NaN になる。
+package indicators
+
+import "math"
+
+// 単純移動平均
+func SMA(values []float64, period int) []float64 {
+	out := nans(len(values))
+	if period <= 0 {
+		return out
+	}
+	var sum float64
+	for i, v := range values {
+		sum += v
+		if i >= period {
+			sum -= values[i-period]
+		}
+		if i >= period-1 {
+			out[i] = sum / float64(period)
+		}
+	}
+	return out
+}
+
+// 指数移動平均 (最初の period 個の単純平均を初期値にする)
+// 先頭が NaN の系列 (MACD など) は、NaN でない最初の位置から計算する
+func EMA(values []float64, period int) []float64 {
+	out := nans(len(values))
+	start := 0
+	for start < len(values) && math.IsNaN(values[start]) {
+		start++
+	}
+	if period <= 0 || len(values)-start < period {
+		return out
+	}
+	var sum float64
+	for _, v := range values[start : start+period] {
+		sum += v
+	}
+	prev := sum / float64(period)
+	out[start+period-1] = prev
+
+	alpha := 2 / float64(period+1)
+	for i := start + period; i < len(values); i++ {
+		prev = alpha*values[i] + (1-alpha)*prev
+		out[i] = prev
+	}
+	return out
+}
+
+// RSI (Wilder の平滑化)。0〜100
+func RSI(closes []float64, period int) []float64 {
+	out := nans(len(closes))
+	if period <= 0 || len(closes) <= period {
+		return out
+	}
+	var gain, loss float64
+	for i := 1; i <= period; i++ {
+		g, l := change(closes[i-1], closes[i])
+		gain += g
+		loss += l
+	}
+	gain /= float64(period)
+	loss /= float64(period)
+	out[period] = rsi(gain, loss)
+
+	for i := period + 1; i < len(closes); i++ {
+		g, l := change(closes[i-1], closes[i])
+		gain = (gain*float64(period-1) + g) / float64(period)
+		loss = (loss*float64(period-1) + l) / float64(period)
+		out[i] = rsi(gain, loss)
+	}
+	return out
+}
+
+func change(prev, cur float64) (gain, loss float64) {
+	if d := cur - prev; d > 0 {
+		return d, 0
+	}
+	return 0, prev - cur
+}
+
+func rsi(gain, loss float64) float64 {
+	if loss == 0 {
+		if gain == 0 {
+			return 50
+		}
+		return 100
+	}
+	return 100 - 100/(1+gain/loss)
+}
+
+// MACD の各系列
+type MACDSeries struct {
+	MACD      []float64 // 短期EMA - 長期EMA
+	Signal    []float64 // MACD の EMA
+	Histogram []float64 // MACD - Signal
+}
+
+// MACD (一般的には fast=12, slow=26, signal=9)
+func MACD(closes []float64, fast, slow, signal int) MACDSeries {
+	fastEMA, slowEMA := EMA(closes, fast), EMA(closes, slow)
+	m := MACDSeries{MACD: nans(len(closes)), Histogram: nans(len(closes))}
+	for i := range closes {
+		m.MACD[i] = fastEMA[i] - slowEMA[i] // どちらかが NaN なら NaN
+	}
+	m.Signal = EMA(m.MACD, signal)
+	for i := range closes {
+		m.Histogram[i] = m.MACD[i] - m.Signal[i]
+	}
+	return m
+}
+
+// ATR (Wilder の平滑化)。前日終値をまたぐ窓開けも値幅に含める
+func ATR(highs, lows, closes []float64, period int) []float64 {
+	n := min(len(highs), len(lows), len(closes))
+	out := nans(n)
+	if period <= 0 || n <= period {
+		return out
+	}
+	tr := func(i int) float64 {
+		return max(highs[i]-lows[i], math.Abs(highs[i]-closes[i-1]), math.Abs(lows[i]-closes[i-1]))
+	}
+	var sum float64
+	for i := 1; i <= period; i++ {
+		sum += tr(i)
+	}
+	prev := sum / float64(period)
+	out[period] = prev
+	for i := period + 1; i < n; i++ {
+		prev = (prev*float64(period-1) + tr(i)) / float64(period)
+		out[i] = prev
+	}
+	return out
+}
+
+// ボリンジャーバンドの各系列
+type BollingerSeries struct {
+	Middle []float64
+	Upper  []float64
+	Lower  []float64
+}
+
+// ボリンジャーバンド (中心は SMA、幅は母標準偏差の k 倍。一般的には period=20, k=2)
+func Bollinger(closes []float64, period int, k float64) BollingerSeries {
+	b := BollingerSeries{Middle: SMA(closes, period), Upper: nans(len(closes)), Lower: nans(len(closes))}
+	for i := range closes {
+		if math.IsNaN(b.Middle[i]) {
+			continue
+		}
+		sd := stddev(closes[i-period+1:i+1], b.Middle[i])
+		b.Upper[i] = b.Middle[i] + k*sd
+		b.Lower[i] = b.Middle[i] - k*sd
+	}
+	return b
+}
+
+// 出来高Zスコア: その日の出来高が直前 period 日の平均から標準偏差いくつ分離れているか
+// 直前の出来高が一定 (標準偏差0) の日は NaN
+func VolumeZScore(volumes []float64, period int) []float64 {
+	out := nans(len(volumes))
+	if period <= 1 {
+		return out
+	}
+	for i := period; i < len(volumes); i++ {
+		window := volumes[i-period : i]
+		var sum float64
+		for _, v := range window {
+			sum += v
+		}
+		mean := sum / float64(period)
+		if sd := stddev(window, mean); sd > 0 {
+			out[i] = (volumes[i] - mean) / sd
+		}
+	}
+	return out
+}
+
+// 期間中の高値・安値と、最新の終値のそれぞれからの距離
+type HighLowDistance struct {
+	High, Low         float64
+	FromHigh, FromLow float64 // (終値 - 高値) / 高値, (終値 - 安値) / 安値 (%)
+	Sessions          int     // 実際に使った日数
+}
+
+// 直近 period 日 (足りなければある分だけ) の高値・安値からの距離。0 以下の高値・安値 (売買なしの日) は無視する
+// FromHigh は 0 以下、FromLow は 0 以上になる
+// 52週なら period は約245営業日
+func DistanceFromHighLow(highs, lows, closes []float64, period int) (HighLowDistance, bool) {
+	n := min(len(highs), len(lows), len(closes))
+	if n == 0 || period <= 0 {
+		return HighLowDistance{}, false
+	}
+	start := max(0, n-period)
+	d := HighLowDistance{Sessions: n - start}
+	for i := start; i < n; i++ {
+		// 売買が成立しなかった日は高値・安値が 0 (null) なので飛ばす
+		if highs[i] > 0 {
+			d.High = max(d.High, highs[i])
+		}
+		if lows[i] > 0 && (d.Low == 0 || lows[i] < d.Low) {
+			d.Low = lows[i]
+		}
+	}
+	last := closes[n-1]
+	if d.High <= 0 || d.Low <= 0 || last <= 0 {
+		return HighLowDistance{}, false
+	}
+	d.FromHigh = (last - d.High) / d.High * 100
+	d.FromLow = (last - d.Low) / d.Low * 100
+	return d, true
+}
+
+// 系列の最後の値 (NaN なら false)
+func Last(series []float64) (float64, bool) {
+	if len(series) == 0 || math.IsNaN(series[len(series)-1]) {
+		return 0, false
+	}
+	return series[len(series)-1], true
+}
+
+func stddev(values []float64, mean float64) float64 {
+	var sq float64
+	for _, v := range values {
+		sq += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(sq / float64(len(values)))
+}
+
+func nans(n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = math.NaN()
+	}
+	return out
+}
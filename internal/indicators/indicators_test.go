@@ -0,0 +1,198 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+)
+
+var nan = math.NaN()
+
+// NaN 同士は一致とみなして系列を比べる
+func assertSeries(t *testing.T, name string, got, want []float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: len = %d, want %d (%v)", name, len(got), len(want), got)
+	}
+	for i := range want {
+		if math.IsNaN(want[i]) != math.IsNaN(got[i]) || (!math.IsNaN(want[i]) && math.Abs(got[i]-want[i]) > 1e-9) {
+			t.Errorf("%s: [%d] = %v, want %v (got %v)", name, i, got[i], want[i], got)
+			return
+		}
+	}
+}
+
+func TestSMA(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		period int
+		want   []float64
+	}{
+		{"basic", []float64{1, 2, 3, 4, 5}, 3, []float64{nan, nan, 2, 3, 4}},
+		{"period 1", []float64{4, 8}, 1, []float64{4, 8}},
+		{"shorter than period", []float64{1, 2}, 3, []float64{nan, nan}},
+		{"zero period", []float64{1, 2}, 0, []float64{nan, nan}},
+		{"empty", nil, 3, []float64{}},
+	}
+	for _, tt := range tests {
+		assertSeries(t, tt.name, SMA(tt.values, tt.period), tt.want)
+	}
+}
+
+func TestEMA(t *testing.T) {
+	// period 3 は alpha = 0.5。初期値は最初の3つの平均 (2+4+6)/3 = 4
+	tests := []struct {
+		name   string
+		values []float64
+		period int
+		want   []float64
+	}{
+		{"basic", []float64{2, 4, 6, 8, 4}, 3, []float64{nan, nan, 4, 6, 5}},
+		{"leading NaN", []float64{nan, 2, 4, 6, 8}, 3, []float64{nan, nan, nan, 4, 6}},
+		{"shorter than period", []float64{1, 2}, 3, []float64{nan, nan}},
+		{"too few after NaN", []float64{nan, nan, 1, 2}, 3, []float64{nan, nan, nan, nan}},
+	}
+	for _, tt := range tests {
+		assertSeries(t, tt.name, EMA(tt.values, tt.period), tt.want)
+	}
+}
+
+func TestRSI(t *testing.T) {
+	// 変化: +1, -1, +2, 0
+	// 初期: 平均上昇 0.5 / 平均下落 0.5 → 50
+	// 次: 上昇 (0.5+2)/2=1.25 / 下落 (0.5+0)/2=0.25 → RS 5 → 100-100/6
+	// 次: 上昇 0.625 / 下落 0.125 → RS 5
+	tests := []struct {
+		name   string
+		closes []float64
+		period int
+		want   []float64
+	}{
+		{"basic", []float64{10, 11, 10, 12, 12}, 2, []float64{nan, nan, 50, 250.0 / 3, 250.0 / 3}},
+		{"flat", []float64{5, 5, 5, 5}, 2, []float64{nan, nan, 50, 50}},
+		{"only gains", []float64{1, 2, 3, 4}, 2, []float64{nan, nan, 100, 100}},
+		{"only losses", []float64{4, 3, 2, 1}, 2, []float64{nan, nan, 0, 0}},
+		{"same length as period", []float64{1, 2}, 2, []float64{nan, nan}},
+	}
+	for _, tt := range tests {
+		assertSeries(t, tt.name, RSI(tt.closes, tt.period), tt.want)
+	}
+}
+
+func TestMACD(t *testing.T) {
+	// EMA2 (alpha 2/3): 3, 5, 7, 5 / EMA3: 4, 6, 5
+	// MACD: 1, 1, 0 / Signal (EMA2 of MACD): 1, 1/3
+	m := MACD([]float64{2, 4, 6, 8, 4}, 2, 3, 2)
+	assertSeries(t, "macd", m.MACD, []float64{nan, nan, 1, 1, 0})
+	assertSeries(t, "signal", m.Signal, []float64{nan, nan, nan, 1, 1.0 / 3})
+	assertSeries(t, "histogram", m.Histogram, []float64{nan, nan, nan, 0, -1.0 / 3})
+
+	short := MACD([]float64{1, 2}, 2, 3, 2)
+	assertSeries(t, "short macd", short.MACD, []float64{nan, nan})
+	assertSeries(t, "short signal", short.Signal, []float64{nan, nan})
+	assertSeries(t, "short histogram", short.Histogram, []float64{nan, nan})
+}
+
+func TestATR(t *testing.T) {
+	// TR: 3 (12-9), 2 (11-9), 5 (窓開け: 15 - 前日終値 10)
+	// 初期: (3+2)/2 = 2.5、次: (2.5*1 + 5)/2 = 3.75
+	highs := []float64{10, 12, 11, 15}
+	lows := []float64{8, 9, 9, 14}
+	closes := []float64{9, 11, 10, 14.5}
+	tests := []struct {
+		name               string
+		highs, lows, close []float64
+		period             int
+		want               []float64
+	}{
+		{"with gap", highs, lows, closes, 2, []float64{nan, nan, 2.5, 3.75}},
+		{"same length as period", highs[:2], lows[:2], closes[:2], 2, []float64{nan, nan}},
+		{"uneven lengths use the shortest", highs, lows, closes[:3], 2, []float64{nan, nan, 2.5}},
+	}
+	for _, tt := range tests {
+		assertSeries(t, tt.name, ATR(tt.highs, tt.lows, tt.close, tt.period), tt.want)
+	}
+}
+
+func TestBollinger(t *testing.T) {
+	// [1,2,3]: 平均 2、母分散 2/3 / [2,3,6]: 平均 11/3、母分散 26/9
+	sd1, sd2 := math.Sqrt(2.0/3), math.Sqrt(26)/3
+	b := Bollinger([]float64{1, 2, 3, 6}, 3, 2)
+	assertSeries(t, "middle", b.Middle, []float64{nan, nan, 2, 11.0 / 3})
+	assertSeries(t, "upper", b.Upper, []float64{nan, nan, 2 + 2*sd1, 11.0/3 + 2*sd2})
+	assertSeries(t, "lower", b.Lower, []float64{nan, nan, 2 - 2*sd1, 11.0/3 - 2*sd2})
+
+	// 横ばいなら幅0 (NaN にはしない)
+	flat := Bollinger([]float64{5, 5, 5}, 3, 2)
+	assertSeries(t, "flat upper", flat.Upper, []float64{nan, nan, 5})
+	assertSeries(t, "flat lower", flat.Lower, []float64{nan, nan, 5})
+
+	short := Bollinger([]float64{1, 2}, 3, 2)
+	assertSeries(t, "short upper", short.Upper, []float64{nan, nan})
+}
+
+func TestVolumeZScore(t *testing.T) {
+	// 直前3日 [100,200,300]: 平均 200、母標準偏差 sqrt(20000/3) → (400-200)/sd = sqrt(6)
+	// 直前3日 [200,300,400]: 平均 300、同じ標準偏差 → (200-300)/sd = -sqrt(6)/2
+	tests := []struct {
+		name    string
+		volumes []float64
+		period  int
+		want    []float64
+	}{
+		{"basic", []float64{100, 200, 300, 400, 200}, 3, []float64{nan, nan, nan, math.Sqrt(6), -math.Sqrt(6) / 2}},
+		{"flat window", []float64{100, 100, 100, 500}, 3, []float64{nan, nan, nan, nan}},
+		{"same length as period", []float64{100, 200, 300}, 3, []float64{nan, nan, nan}},
+		{"period 1", []float64{100, 200}, 1, []float64{nan, nan}},
+	}
+	for _, tt := range tests {
+		assertSeries(t, tt.name, VolumeZScore(tt.volumes, tt.period), tt.want)
+	}
+}
+
+func TestDistanceFromHighLow(t *testing.T) {
+	highs := []float64{10, 12, 11}
+	lows := []float64{8, 9, 7}
+	closes := []float64{9, 11, 10.8}
+	tests := []struct {
+		name               string
+		highs, lows, close []float64
+		period             int
+		want               HighLowDistance
+		ok                 bool
+	}{
+		// 直近2日: 高値 12、安値 7
+		{"window", highs, lows, closes, 2, HighLowDistance{High: 12, Low: 7, FromHigh: -10, FromLow: 3.8 / 7 * 100, Sessions: 2}, true},
+		{"shorter than period", highs, lows, closes, 245, HighLowDistance{High: 12, Low: 7, FromHigh: -10, FromLow: 3.8 / 7 * 100, Sessions: 3}, true},
+		{"empty", nil, nil, nil, 2, HighLowDistance{}, false},
+		// 売買なしの日の 0 は高値・安値にしない
+		{"zero low", highs, []float64{8, 0, 7}, closes, 3, HighLowDistance{High: 12, Low: 7, FromHigh: -10, FromLow: 3.8 / 7 * 100, Sessions: 3}, true},
+		{"no-trade day", []float64{10, 0, 11}, []float64{8, 0, 7}, []float64{9, 0, 10.8}, 3, HighLowDistance{High: 11, Low: 7, FromHigh: -0.2 / 11 * 100, FromLow: 3.8 / 7 * 100, Sessions: 3}, true},
+		{"no trades", []float64{0, 0}, []float64{0, 0}, []float64{0, 0}, 2, HighLowDistance{}, false},
+		{"no trade on last day", highs, lows, []float64{9, 11, 0}, 3, HighLowDistance{}, false},
+		{"zero period", highs, lows, closes, 0, HighLowDistance{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := DistanceFromHighLow(tt.highs, tt.lows, tt.close, tt.period)
+		if ok != tt.ok {
+			t.Errorf("%s: ok = %v, want %v", tt.name, ok, tt.ok)
+			continue
+		}
+		if got.High != tt.want.High || got.Low != tt.want.Low || got.Sessions != tt.want.Sessions ||
+			math.Abs(got.FromHigh-tt.want.FromHigh) > 1e-9 || math.Abs(got.FromLow-tt.want.FromLow) > 1e-9 {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLast(t *testing.T) {
+	if v, ok := Last([]float64{1, 2}); !ok || v != 2 {
+		t.Errorf("Last = %v, %v; want 2, true", v, ok)
+	}
+	if _, ok := Last([]float64{1, nan}); ok {
+		t.Error("Last of NaN should be false")
+	}
+	if _, ok := Last(nil); ok {
+		t.Error("Last of empty should be false")
+	}
+}
@@ -0,0 +1,126 @@
+package jquants
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// J-Quants の IDToken の有効期限は発行から24時間
+	idTokenLifetime = 24 * time.Hour
+	// 期限切れ直前のリクエストが401にならないよう、この時間だけ早めに更新する
+	idTokenRefreshMargin = 10 * time.Minute
+)
+
+func (c *Client) Authenticate() error {
+	return c.AuthenticateContext(context.Background())
+}
+
+// リフレッシュトークンから IDToken を取得し直す (有効期限に関係なく強制的に更新)
+func (c *Client) AuthenticateContext(ctx context.Context) error {
+	_, err := c.idTokenFor(ctx, true)
+	return err
+}
+
+// 有効な IDToken を返す。未取得・期限切れ間近なら更新してから返す
+func (c *Client) token(ctx context.Context) (string, error) {
+	return c.idTokenFor(ctx, false)
+}
+
+// 実行中の IDToken 更新。完了すると done が閉じられる
+type tokenRefresh struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+// 複数 goroutine が同時に auth_refresh を叩かないよう、更新は1つだけ走らせて他はその結果を待つ
+// 更新中 (再試行の待ちを含む) はロックを持たず、待つ側も ctx のキャンセルで抜ける
+func (c *Client) idTokenFor(ctx context.Context, force bool) (string, error) {
+	for {
+		c.mu.Lock()
+		if !force && c.idToken != "" && time.Now().Before(c.idTokenExpiry.Add(-idTokenRefreshMargin)) {
+			token := c.idToken
+			c.mu.Unlock()
+			return token, nil
+		}
+		r := c.refreshing
+		if r == nil {
+			r = &tokenRefresh{done: make(chan struct{})}
+			c.refreshing = r
+			c.mu.Unlock()
+
+			var expiry time.Time
+			r.token, expiry, r.err = c.fetchIDToken(ctx)
+			c.mu.Lock()
+			if r.err == nil {
+				c.idToken, c.idTokenExpiry = r.token, expiry
+			}
+			c.refreshing = nil
+			c.mu.Unlock()
+			close(r.done)
+			return r.token, r.err
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-r.done:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		// 更新した側の ctx がキャンセルされただけなら、自分の ctx で取り直す
+		if r.err != nil && ctx.Err() == nil && (errors.Is(r.err, context.Canceled) || errors.Is(r.err, context.DeadlineExceeded)) {
+			continue
+		}
+		return r.token, r.err
+	}
+}
+
+// 401 を受けたトークンを無効化する
+// 別の goroutine が既に更新済みの場合は新しいトークンを消さないよう、同じトークンの時だけクリアする
+func (c *Client) invalidateToken(stale string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.idToken == stale {
+		c.idToken = ""
+	}
+}
+
+// auth_refresh で新しい IDToken と有効期限を取得する (c.mu は持たずに呼ぶ)
+func (c *Client) fetchIDToken(ctx context.Context) (string, time.Time, error) {
+	q := url.Values{}
+	q.Set("refreshtoken", c.RefreshToken)
+
+	// 他の API と同じくレート制限と 429 / 5xx の再試行に従う
+	issuedAt := time.Now()
+	resp, retries, err := c.sendWithRetry(ctx, func() (*http.Request, error) {
+		issuedAt = time.Now()
+		return c.newRequest(ctx, "POST", AuthEndpoint, q)
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return "", time.Time{}, newAPIError(AuthEndpoint, resp.StatusCode, body, retries)
+	}
+
+	var result struct {
+		IDToken string `json:"idToken"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", time.Time{}, err
+	}
+	if result.IDToken == "" {
+		return "", time.Time{}, fmt.Errorf("auth failed: empty idToken in response")
+	}
+	return result.IDToken, issuedAt.Add(idTokenLifetime), nil
+}
@@ -0,0 +1,105 @@
+package jquants_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquantstest"
+)
+
+// auth_refresh も他の API と同じく 429 / 5xx を再試行する
+func TestAuthRefreshRetries(t *testing.T) {
+	for _, status := range []int{http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+		srv := jquantstest.NewServer(nil)
+		srv.InjectError(jquants.AuthEndpoint, jquantstest.InjectedError{Status: status, RetryAfter: "0", Times: 1})
+
+		infos, err := srv.JQuantsClient().GetListedInfo(context.Background(), "72030", "")
+		if err != nil {
+			t.Fatalf("status %d: GetListedInfo: %v", status, err)
+		}
+		if len(infos) == 0 {
+			t.Errorf("status %d: no listed info", status)
+		}
+		if n := srv.Requests(jquants.AuthEndpoint); n != 2 {
+			t.Errorf("status %d: auth_refresh requests = %d, want 2", status, n)
+		}
+		srv.Close()
+	}
+}
+
+func TestAuthRefreshRateLimitedGivesUp(t *testing.T) {
+	srv := jquantstest.NewServer(nil)
+	defer srv.Close()
+	srv.InjectError(jquants.AuthEndpoint, jquantstest.InjectedError{Status: http.StatusTooManyRequests, RetryAfter: "0"})
+
+	err := srv.JQuantsClient().AuthenticateContext(context.Background())
+	if !errors.Is(err, jquants.ErrRateLimited) {
+		t.Fatalf("err = %v, want ErrRateLimited", err)
+	}
+	// レート制限はトークンの問題ではないので、認証エラーとして扱わない (バッチを中断させない)
+	if errors.Is(err, jquants.ErrUnauthorized) {
+		t.Errorf("err = %v, should not be ErrUnauthorized", err)
+	}
+	if !strings.Contains(err.Error(), "after 2 retries") {
+		t.Errorf("err = %v, want retry count in message", err)
+	}
+	if n := srv.Requests(jquants.AuthEndpoint); n != 3 {
+		t.Errorf("auth_refresh requests = %d, want 3", n)
+	}
+}
+
+// 同時に呼ばれても auth_refresh は1回だけ
+func TestAuthRefreshSharedByConcurrentCalls(t *testing.T) {
+	srv := jquantstest.NewServer(nil)
+	defer srv.Close()
+	c := srv.JQuantsClient()
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.GetListedInfo(context.Background(), "72030", ""); err != nil {
+				t.Errorf("GetListedInfo: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := srv.Requests(jquants.AuthEndpoint); n != 1 {
+		t.Errorf("auth_refresh requests = %d, want 1", n)
+	}
+}
+
+// 他の goroutine の更新 (再試行の待ち) を待っている間も ctx のキャンセルで抜ける
+func TestAuthRefreshWaitHonoursContext(t *testing.T) {
+	srv := jquantstest.NewServer(nil)
+	defer srv.Close()
+	srv.InjectError(jquants.AuthEndpoint, jquantstest.InjectedError{Status: http.StatusTooManyRequests, RetryAfter: "1", Times: 1})
+	c := srv.JQuantsClient(jquants.WithRetryPolicy(jquants.RetryPolicy{MaxRetries: 1, BaseDelay: time.Second, MaxDelay: time.Second}))
+
+	refreshed := make(chan error, 1)
+	go func() { refreshed <- c.AuthenticateContext(context.Background()) }()
+	for srv.Requests(jquants.AuthEndpoint) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := c.GetListedInfo(ctx, "72030", "")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("waited %v for the other refresh, want to return on cancel", elapsed)
+	}
+	if err := <-refreshed; err != nil {
+		t.Errorf("AuthenticateContext: %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+package jquants
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 当日以降のデータ (まだ更新されうるもの) のキャッシュ有効期間
+const DefaultCacheTTL = 15 * time.Minute
+
+// J-Quants の日付は日本時間基準
+var jst = time.FixedZone("JST", 9*60*60)
+
+// レスポンスキャッシュのインターフェース
+// キーは接続先+エンドポイント+クエリ文字列、値はレスポンスボディ (1ページ分の JSON)
+type Cache interface {
+	// 有効期限内のデータがあれば返す
+	Get(key string) ([]byte, bool)
+	// ttl が 0 の場合は無期限に保持する
+	Set(key string, data []byte, ttl time.Duration) error
+	// すべてのキャッシュを削除する
+	Purge() error
+}
+
+// レスポンスキャッシュを設定する。nil ならキャッシュしない
+func WithCache(cache Cache) Option {
+	return func(c *Client) { c.cache = cache }
+}
+
+// 接続先 (偽サーバーなど) のレスポンスが本番 API の結果として読まれないよう、ベース URL もキーに含める
+func cacheKey(baseURL string, path string, q url.Values) string {
+	return baseURL + path + "?" + q.Encode()
+}
+
+// 対象日より遅れて公表されるデータの、公表までの最大日数 (暦日・連休を見込んだ余裕を含む)
+// 公表前に取得すると空や一部だけの結果になるので、この期間内の日付を含むリクエストは無期限にしない
+var publicationLagDays = map[string]int{
+	FinsEndpoint:                 3, // 夜間の開示は翌日以降に反映されることがある
+	FSDetailsEndpoint:            3,
+	DividendEndpoint:             3,
+	ShortSellingEndpoint:         3,  // 翌営業日に公表
+	WeeklyMarginInterestEndpoint: 10, // 週末時点の残高が翌週第2営業日に公表
+	TradesSpecEndpoint:           10, // 週次で翌週第4営業日に公表
+}
+
+// クエリの日付パラメータからキャッシュ期間を決める
+// 過去日 (遅れて公表されるデータは公表済みのはずの日付) だけを対象にしたリクエストは結果が変わらないので無期限、
+// 当日や公表前かもしれない日付を含む・日付指定なし (最新データ) の場合は短い TTL にする
+// ただし日足の調整済み価格は取得時点までの分割で調整されていて、過去日でも後から変わる。
+// 取得日の違うページが混ざると調整の基準がずれるので、日付が変わる (日本時間) までしか持たない
+func cacheTTL(path string, q url.Values) time.Duration {
+	ttl := cacheTTLByDate(q, publicationLagDays[path])
+	if path == DailyQuotesEndpoint && ttl == 0 {
+		now := time.Now().In(jst)
+		y, m, d := now.Date()
+		return time.Date(y, m, d+1, 0, 0, 0, 0, jst).Sub(now)
+	}
+	return ttl
+}
+
+func cacheTTLByDate(q url.Values, lagDays int) time.Duration {
+	var latest string
+	for _, key := range []string{"date", "to"} {
+		if v := q.Get(key); v != "" {
+			latest = v
+			break
+		}
+	}
+	if latest == "" {
+		return DefaultCacheTTL
+	}
+
+	d, ok := parseAPIDate(latest)
+	if !ok {
+		return DefaultCacheTTL
+	}
+	published := time.Now().In(jst).AddDate(0, 0, -lagDays).Format("2006-01-02")
+	if d.Format("2006-01-02") < published {
+		return 0
+	}
+	return DefaultCacheTTL
+}
+
+// J-Quants は YYYY-MM-DD と YYYYMMDD の両方を受け付ける
+func parseAPIDate(s string) (time.Time, bool) {
+	for _, layout := range []string{"2006-01-02", "20060102"} {
+		if t, err := time.ParseInLocation(layout, s, jst); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ディレクトリにキー毎のファイルとして保存するキャッシュ
+type FileCache struct {
+	Dir string
+}
+
+func NewFileCache(dir string) (*FileCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileCache{Dir: dir}, nil
+}
+
+type fileCacheEntry struct {
+	Key       string          `json:"key"`
+	ExpiresAt time.Time       `json:"expires_at"` // ゼロ値なら無期限
+	Body      json.RawMessage `json:"body"`
+}
+
+func (f *FileCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(f.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (f *FileCache) Get(key string) ([]byte, bool) {
+	data, err := os.ReadFile(f.path(key))
+	if err != nil {
+		return nil, false
+	}
+
+	var entry fileCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Key != key {
+		return nil, false
+	}
+	if !entry.ExpiresAt.IsZero() && time.Now().After(entry.ExpiresAt) {
+		return nil, false
+	}
+	return entry.Body, true
+}
+
+func (f *FileCache) Set(key string, body []byte, ttl time.Duration) error {
+	entry := fileCacheEntry{Key: key, Body: body}
+	if ttl > 0 {
+		entry.ExpiresAt = time.Now().Add(ttl)
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	// 書き込み途中のファイルを他のプロセスが読まないよう、一時ファイルに書いてから rename する
+	tmp, err := os.CreateTemp(f.Dir, "tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path(key))
+}
+
+func (f *FileCache) Purge() error {
+	entries, err := os.ReadDir(f.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := os.RemoveAll(filepath.Join(f.Dir, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package jquants
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCacheTTL(t *testing.T) {
+	past := url.Values{"code": {"72030"}, "from": {"2025-06-02"}, "to": {"2025-07-31"}}
+	today := url.Values{"code": {"72030"}, "date": {time.Now().In(jst).Format("2006-01-02")}}
+
+	if ttl := cacheTTL(FinsEndpoint, past); ttl != 0 {
+		t.Errorf("past statements: ttl = %v, want 0 (no expiry)", ttl)
+	}
+	if ttl := cacheTTL(FinsEndpoint, today); ttl != DefaultCacheTTL {
+		t.Errorf("today's statements: ttl = %v, want %v", ttl, DefaultCacheTTL)
+	}
+	// 日足は後の分割で調整済み価格が変わるので、過去日でも日付が変わるまで
+	if ttl := cacheTTL(DailyQuotesEndpoint, past); ttl <= 0 || ttl > 24*time.Hour {
+		t.Errorf("past daily quotes: ttl = %v, want until end of day", ttl)
+	}
+	if ttl := cacheTTL(DailyQuotesEndpoint, today); ttl != DefaultCacheTTL {
+		t.Errorf("today's daily quotes: ttl = %v, want %v", ttl, DefaultCacheTTL)
+	}
+}
+
+// 遅れて公表されるデータは、公表前かもしれない最近の日付を含むリクエストを無期限にしない
+func TestCacheTTLPublicationLag(t *testing.T) {
+	daysAgo := func(n int) url.Values {
+		return url.Values{"from": {"2025-01-01"}, "to": {time.Now().In(jst).AddDate(0, 0, -n).Format("2006-01-02")}}
+	}
+	tests := []struct {
+		name string
+		path string
+		q    url.Values
+		want time.Duration
+	}{
+		{"margin interest last week", WeeklyMarginInterestEndpoint, daysAgo(5), DefaultCacheTTL},
+		{"margin interest long ago", WeeklyMarginInterestEndpoint, daysAgo(30), 0},
+		{"trades_spec last week", TradesSpecEndpoint, daysAgo(7), DefaultCacheTTL},
+		{"statements yesterday", FinsEndpoint, daysAgo(1), DefaultCacheTTL},
+		{"statements last month", FinsEndpoint, daysAgo(30), 0},
+		// 遅れのないデータは前日でも確定
+		{"calendar yesterday", TradingCalendarEndpoint, daysAgo(1), 0},
+	}
+	for _, tt := range tests {
+		if ttl := cacheTTL(tt.path, tt.q); ttl != tt.want {
+			t.Errorf("%s: ttl = %v, want %v", tt.name, ttl, tt.want)
+		}
+	}
+}
+
+// 偽サーバーのレスポンスを本番 API と同じキーで保存しない
+func TestCacheKeyIncludesBaseURL(t *testing.T) {
+	q := url.Values{"code": {"72030"}, "date": {"2025-07-10"}}
+	if cacheKey(BaseURL, DailyQuotesEndpoint, q) == cacheKey("http://localhost:8080", DailyQuotesEndpoint, q) {
+		t.Error("cache keys for the real API and a fake server must differ")
+	}
+}
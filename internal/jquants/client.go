@@ -1,192 +1,371 @@
 package jquants
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
+	"iter"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
 )
 
 const (
 	BaseURL      = "https://api.jquants.com/v1"
 	AuthEndpoint = "/token/auth_refresh"
 	FinsEndpoint = "/fins/statements"
+
+	ListedInfoEndpoint  = "/listed/info"
+	DailyQuotesEndpoint = "/prices/daily_quotes"
 )
 
+// デフォルトのHTTPタイムアウト (J-Quants が応答しない場合に永久にブロックしないように)
+const DefaultTimeout = 30 * time.Second
+
 type Client struct {
 	RefreshToken string
-	IDToken      string
+
+	// IDToken は複数 goroutine から読み書きされるので mu で保護する (auth.go)
+	mu            sync.Mutex
+	idToken       string
+	idTokenExpiry time.Time
+	refreshing    *tokenRefresh // 実行中の IDToken 更新 (なければ nil)
+
+	httpClient *http.Client
+	baseURL    string
+	userAgent  string
+	timeout    *time.Duration
+	limiter    *RateLimiter
+	retry      RetryPolicy
+	cache      Cache
 }
 
-func NewClient(refreshToken string) *Client {
-	return &Client{RefreshToken: refreshToken}
+// NewClient のオプション
+type Option func(*Client)
+
+// 任意の *http.Client を使う (Transport の差し替えやテスト用)
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
 }
 
-func (c *Client) Authenticate() error {
-	url := fmt.Sprintf("%s%s?refreshtoken=%s", BaseURL, AuthEndpoint, c.RefreshToken)
-	req, err := http.NewRequest("POST", url, nil)
-	if err != nil {
-		return err
-	}
+// 接続先のベースURLを変更する (末尾の "/" は不要)
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) { c.baseURL = strings.TrimRight(baseURL, "/") }
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
+// リクエスト全体のタイムアウトを設定する。0 以下ならタイムアウトなし
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		d = max(d, 0)
+		c.timeout = &d
 	}
-	defer resp.Body.Close()
+}
+
+// User-Agent ヘッダーを設定する
+func WithUserAgent(ua string) Option {
+	return func(c *Client) { c.userAgent = ua }
+}
 
-	if resp.StatusCode != 200 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("auth failed: status %d body: %s", resp.StatusCode, string(body))
+func NewClient(refreshToken string, opts ...Option) *Client {
+	c := &Client{
+		RefreshToken: refreshToken,
+		baseURL:      BaseURL,
+		limiter:      NewRateLimiter(DefaultRequestsPerSecond, DefaultBurst),
+		retry:        DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
 
-	var result struct {
-		IDToken string `json:"idToken"`
+	if c.httpClient == nil {
+		c.httpClient = &http.Client{Timeout: DefaultTimeout}
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return err
+	// 渡された *http.Client を書き換えないようにコピーしてからタイムアウトを設定する
+	if c.timeout != nil {
+		hc := *c.httpClient
+		hc.Timeout = *c.timeout
+		c.httpClient = &hc
 	}
-	c.IDToken = result.IDToken
-	return nil
+	return c
 }
 
-type FinancialStatement struct {
-	LocalCode       string `json:"LocalCode"`
-	DisclosedDate   string `json:"DisclosedDate"`
-	
-	// 実績
-	OperatingProfit string `json:"OperatingProfit"`
-	
-	// 今期予想
-	ForecastNetSales        string `json:"ForecastNetSales"`
-	ForecastOperatingProfit string `json:"ForecastOperatingProfit"`
+// ベースURL・User-Agent を反映したリクエストを作る
+func (c *Client) newRequest(ctx context.Context, method string, path string, q url.Values) (*http.Request, error) {
+	u := c.baseURL + path
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
 
-	// 来期予想
-	NextYearForecastNetSales        string `json:"NextYearForecastNetSales"`
-	NextYearForecastOperatingProfit string `json:"NextYearForecastOperatingProfit"`
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	return req, nil
 }
 
+// 指定日に開示された決算情報を全ページ分取得する
 func (c *Client) GetStatements(targetDate string) ([]FinancialStatement, error) {
-	if c.IDToken == "" {
-		if err := c.Authenticate(); err != nil {
-			return nil, err
-		}
-	}
+	return c.GetStatementsContext(context.Background(), targetDate)
+}
+
+func (c *Client) GetStatementsContext(ctx context.Context, targetDate string) ([]FinancialStatement, error) {
+	return collectPages(c.StatementPages(ctx, targetDate))
+}
 
-	// 修正: dateパラメータを付与
-	url := fmt.Sprintf("%s%s?date=%s", BaseURL, FinsEndpoint, targetDate)
+// 指定日に開示された決算情報を1ページずつ返すイテレータ
+// 全件をメモリに載せずに処理したい場合に使う
+func (c *Client) StatementPages(ctx context.Context, targetDate string) iter.Seq2[[]FinancialStatement, error] {
+	params := url.Values{}
+	params.Set("date", targetDate)
+	return fetchPages[FinancialStatement](ctx, c, FinsEndpoint, params, "statements")
+}
 
-	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Set("Authorization", "Bearer "+c.IDToken)
+// 銘柄の過去の決算情報 (決算短信・業績予想の修正など) をすべて取得する
+// code は4桁でも5桁でもよい
+func (c *Client) GetStatementsByCode(ctx context.Context, code string) ([]FinancialStatement, error) {
+	params := url.Values{}
+	params.Set("code", code)
+	return collectPages(fetchPages[FinancialStatement](ctx, c, FinsEndpoint, params, "statements"))
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+// 株価データの構造体
+type DailyQuote struct {
+	Date          string  `json:"Date"`
+	Code          string  `json:"Code"`
+	Open          float64 `json:"Open"`
+	High          float64 `json:"High"`
+	Low           float64 `json:"Low"`
+	Close         float64 `json:"Close"`
+	Volume        float64 `json:"Volume"`
+	TurnoverValue float64 `json:"TurnoverValue"`
+
+	// 株式分割・併合を考慮した調整済み価格 (最新日を基準に過去を遡って調整)
+	AdjustmentFactor float64 `json:"AdjustmentFactor"`
+	AdjustmentOpen   float64 `json:"AdjustmentOpen"`
+	AdjustmentHigh   float64 `json:"AdjustmentHigh"`
+	AdjustmentLow    float64 `json:"AdjustmentLow"`
+	AdjustmentClose  float64 `json:"AdjustmentClose"`
+	AdjustmentVolume float64 `json:"AdjustmentVolume"`
+
+	// 前場・後場別の四本値 (プレミアムプランのみ。それ以外のプランでは 0 のまま)
+	MorningOpen               float64 `json:"MorningOpen"`
+	MorningHigh               float64 `json:"MorningHigh"`
+	MorningLow                float64 `json:"MorningLow"`
+	MorningClose              float64 `json:"MorningClose"`
+	MorningVolume             float64 `json:"MorningVolume"`
+	MorningTurnoverValue      float64 `json:"MorningTurnoverValue"`
+	MorningAdjustmentOpen     float64 `json:"MorningAdjustmentOpen"`
+	MorningAdjustmentHigh     float64 `json:"MorningAdjustmentHigh"`
+	MorningAdjustmentLow      float64 `json:"MorningAdjustmentLow"`
+	MorningAdjustmentClose    float64 `json:"MorningAdjustmentClose"`
+	MorningAdjustmentVolume   float64 `json:"MorningAdjustmentVolume"`
+	AfternoonOpen             float64 `json:"AfternoonOpen"`
+	AfternoonHigh             float64 `json:"AfternoonHigh"`
+	AfternoonLow              float64 `json:"AfternoonLow"`
+	AfternoonClose            float64 `json:"AfternoonClose"`
+	AfternoonVolume           float64 `json:"AfternoonVolume"`
+	AfternoonTurnoverValue    float64 `json:"AfternoonTurnoverValue"`
+	AfternoonAdjustmentOpen   float64 `json:"AfternoonAdjustmentOpen"`
+	AfternoonAdjustmentHigh   float64 `json:"AfternoonAdjustmentHigh"`
+	AfternoonAdjustmentLow    float64 `json:"AfternoonAdjustmentLow"`
+	AfternoonAdjustmentClose  float64 `json:"AfternoonAdjustmentClose"`
+	AfternoonAdjustmentVolume float64 `json:"AfternoonAdjustmentVolume"`
+}
 
-	if resp.StatusCode != 200 {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("api error: %d %s", resp.StatusCode, string(body))
+// 価格系列の種類 (生値 or 分割調整済み)
+type PriceMode string
+
+const (
+	PriceRaw      PriceMode = "raw"
+	PriceAdjusted PriceMode = "adjusted"
+)
+
+func ParsePriceMode(s string) (PriceMode, error) {
+	switch m := PriceMode(s); m {
+	case PriceRaw, PriceAdjusted:
+		return m, nil
 	}
+	return "", fmt.Errorf("invalid price mode %q (want %q or %q)", s, PriceRaw, PriceAdjusted)
+}
 
-	bodyBytes, _ := io.ReadAll(resp.Body)
-	var result struct {
-		Statements []FinancialStatement `json:"statements"`
+// mode が PriceAdjusted なら Open/High/Low/Close/Volume (前場・後場も) を調整済みの値に置き換えたコピーを返す
+// 呼び出し側は mode を気にせず Open や Close を参照できる
+func (q DailyQuote) In(mode PriceMode) DailyQuote {
+	if mode != PriceAdjusted {
+		return q
 	}
-	if err := json.Unmarshal(bodyBytes, &result); err != nil {
-		return nil, err
+	q.Open = q.AdjustmentOpen
+	q.High = q.AdjustmentHigh
+	q.Low = q.AdjustmentLow
+	q.Close = q.AdjustmentClose
+	q.Volume = q.AdjustmentVolume
+
+	q.MorningOpen = q.MorningAdjustmentOpen
+	q.MorningHigh = q.MorningAdjustmentHigh
+	q.MorningLow = q.MorningAdjustmentLow
+	q.MorningClose = q.MorningAdjustmentClose
+	q.MorningVolume = q.MorningAdjustmentVolume
+	q.AfternoonOpen = q.AfternoonAdjustmentOpen
+	q.AfternoonHigh = q.AfternoonAdjustmentHigh
+	q.AfternoonLow = q.AfternoonAdjustmentLow
+	q.AfternoonClose = q.AfternoonAdjustmentClose
+	q.AfternoonVolume = q.AfternoonAdjustmentVolume
+	return q
+}
+
+func ApplyPriceMode(quotes []DailyQuote, mode PriceMode) []DailyQuote {
+	out := make([]DailyQuote, len(quotes))
+	for i, q := range quotes {
+		out[i] = q.In(mode)
 	}
-	return result.Statements, nil
+	return out
 }
 
-// 株価データの構造体
-type DailyQuote struct {
-	Date  string  `json:"Date"`
-	Open  float64 `json:"Open"`
-	High  float64 `json:"High"`
-	Low   float64 `json:"Low"`
-	Close float64 `json:"Close"`
-	Volume float64 `json:"Volume"`
+// 日付昇順の quotes の調整済み価格を、生値と AdjustmentFactor から計算し直す (quotes を書き換える)
+// later は quotes の最終日より後の調整係数の積
+// API の調整済み価格は取得した時点の最新日が基準なので、日ごとに取得した値を混ぜる場合や
+// 過去のある時点を基準にしたい場合 (その後の分割を知らない状態) に使う
+func Readjust(quotes []DailyQuote, later float64) {
+	cum := later
+	for i := len(quotes) - 1; i >= 0; i-- {
+		q := &quotes[i]
+		q.AdjustmentOpen, q.AdjustmentHigh, q.AdjustmentLow, q.AdjustmentClose = q.Open*cum, q.High*cum, q.Low*cum, q.Close*cum
+		q.AdjustmentVolume = q.Volume / cum
+		q.MorningAdjustmentOpen, q.MorningAdjustmentHigh, q.MorningAdjustmentLow, q.MorningAdjustmentClose = q.MorningOpen*cum, q.MorningHigh*cum, q.MorningLow*cum, q.MorningClose*cum
+		q.MorningAdjustmentVolume = q.MorningVolume / cum
+		q.AfternoonAdjustmentOpen, q.AfternoonAdjustmentHigh, q.AfternoonAdjustmentLow, q.AfternoonAdjustmentClose = q.AfternoonOpen*cum, q.AfternoonHigh*cum, q.AfternoonLow*cum, q.AfternoonClose*cum
+		q.AfternoonAdjustmentVolume = q.AfternoonVolume / cum
+
+		// その日の係数はその日より前の価格に効く
+		if q.AdjustmentFactor != 0 {
+			cum *= q.AdjustmentFactor
+		}
+	}
 }
 
+// 上場銘柄情報 (/listed/info)
+// MarginCode/MarginCodeName はスタンダード・プレミアムプランのみ
 type ListedInfo struct {
-	Code        string `json:"Code"`
-	CompanyName string `json:"CompanyName"`
-	Sector17CodeName string `json:"Sector17CodeName"`
+	Date               string `json:"Date"` // 情報の適用日
+	Code               string `json:"Code"`
+	CompanyName        string `json:"CompanyName"`
+	CompanyNameEnglish string `json:"CompanyNameEnglish"`
+	Sector17Code       string `json:"Sector17Code"`
+	Sector17CodeName   string `json:"Sector17CodeName"`
+	Sector33Code       string `json:"Sector33Code"`
+	Sector33CodeName   string `json:"Sector33CodeName"`
+	ScaleCategory      string `json:"ScaleCategory"` // TOPIX Core30 / TOPIX Large70 / TOPIX Mid400 / TOPIX Small 1 など
+	MarketCode         string `json:"MarketCode"`
+	MarketCodeName     string `json:"MarketCodeName"`
+	MarginCode         string `json:"MarginCode"` // 1: 信用, 2: 貸借, 3: その他
+	MarginCodeName     string `json:"MarginCodeName"`
 }
 
-// 上場銘柄一覧を取得し、マップ (Code -> Name) を返す
-func (c *Client) GetListedInfoMap() (map[string]string, error) {
-	if c.IDToken == "" {
-		if err := c.Authenticate(); err != nil {
-			return nil, err
-		}
+// 市場区分コード (2022-04-04 の市場再編以降)
+const (
+	MarketPrime    = "0111"
+	MarketStandard = "0112"
+	MarketGrowth   = "0113"
+)
+
+// 4桁の銘柄コードを普通株 (末尾0) の5桁コードにそろえる
+// API は4桁・5桁どちらも受け付けるが、レスポンスの Code は常に5桁
+func NormalizeCode(code string) string {
+	code = strings.TrimSpace(code)
+	if len(code) == 4 {
+		return code + "0"
 	}
+	return code
+}
+
+// 上場銘柄一覧を取得する
+// code が空なら全銘柄、date を指定するとその日時点の情報 (市場区分・業種の変更前の状態など) を返す
+func (c *Client) GetListedInfo(ctx context.Context, code string, date string) ([]ListedInfo, error) {
+	return collectPages(c.ListedInfoPages(ctx, code, date))
+}
 
-	url := fmt.Sprintf("%s/listed/info", BaseURL)
-	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Set("Authorization", "Bearer "+c.IDToken)
+// 上場銘柄一覧を取得し、マップ (Code -> Name) を返す
+func (c *Client) GetListedInfoMap() (map[string]string, error) {
+	return c.GetListedInfoMapContext(context.Background())
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+func (c *Client) GetListedInfoMapContext(ctx context.Context) (map[string]string, error) {
+	infos, err := c.GetListedInfo(ctx, "", "")
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("api error: %d", resp.StatusCode)
+	// 使いやすいように Map に変換
+	nameMap := make(map[string]string)
+	for _, info := range infos {
+		nameMap[info.Code] = info.CompanyName
 	}
+	return nameMap, nil
+}
 
-	var result struct {
-		Info []ListedInfo `json:"info"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+// 指定した銘柄の (最新の) 上場情報を取得する
+func (c *Client) GetCompanyInfo(ctx context.Context, code string) (*ListedInfo, error) {
+	infos, err := c.GetListedInfo(ctx, code, "")
+	if err != nil {
 		return nil, err
 	}
+	if len(infos) == 0 {
+		return nil, fmt.Errorf("listed info for %s: %w", code, ErrNotFound)
+	}
+	return &infos[0], nil
+}
 
-	// 使いやすいように Map に変換
-	nameMap := make(map[string]string)
-	for _, info := range result.Info {
-		nameMap[info.Code] = info.CompanyName
+// 上場銘柄一覧を1ページずつ返すイテレータ (code, date は空なら指定なし)
+func (c *Client) ListedInfoPages(ctx context.Context, code string, date string) iter.Seq2[[]ListedInfo, error] {
+	params := url.Values{}
+	if code != "" {
+		params.Set("code", code)
 	}
-	return nameMap, nil
+	if date != "" {
+		params.Set("date", date)
+	}
+	return fetchPages[ListedInfo](ctx, c, ListedInfoEndpoint, params, "info")
 }
 
 // 指定した銘柄の株価を取得（日付範囲指定）
 // API仕様: /prices/daily_quotes?code=xxxx&from=yyyy-mm-dd&to=yyyy-mm-dd
 func (c *Client) GetDailyQuotes(code string, fromDate string, toDate string) ([]DailyQuote, error) {
-	if c.IDToken == "" {
-		if err := c.Authenticate(); err != nil {
-			return nil, err
-		}
-	}
-
-	url := fmt.Sprintf("%s/prices/daily_quotes?code=%s&from=%s&to=%s", BaseURL, code, fromDate, toDate)
+	return c.GetDailyQuotesContext(context.Background(), code, fromDate, toDate)
+}
 
-	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Set("Authorization", "Bearer "+c.IDToken)
+func (c *Client) GetDailyQuotesContext(ctx context.Context, code string, fromDate string, toDate string) ([]DailyQuote, error) {
+	return collectPages(c.DailyQuotePages(ctx, code, fromDate, toDate))
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+// GetDailyQuotesContext の結果を mode の価格系列に揃えて返す
+func (c *Client) GetDailyQuotesWithMode(ctx context.Context, code string, fromDate string, toDate string, mode PriceMode) ([]DailyQuote, error) {
+	quotes, err := c.GetDailyQuotesContext(ctx, code, fromDate, toDate)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	return ApplyPriceMode(quotes, mode), nil
+}
 
-	if resp.StatusCode != 200 {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("api error: %d %s", resp.StatusCode, string(body))
-	}
+// 指定した銘柄の株価を1ページずつ返すイテレータ
+func (c *Client) DailyQuotePages(ctx context.Context, code string, fromDate string, toDate string) iter.Seq2[[]DailyQuote, error] {
+	params := url.Values{}
+	params.Set("code", code)
+	params.Set("from", fromDate)
+	params.Set("to", toDate)
+	return fetchPages[DailyQuote](ctx, c, DailyQuotesEndpoint, params, "daily_quotes")
+}
 
-	var result struct {
-		DailyQuotes []DailyQuote `json:"daily_quotes"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
-	return result.DailyQuotes, nil
-}
\ No newline at end of file
+// 指定日の全銘柄の株価を取得する (一括ダウンロード用)
+func (c *Client) GetDailyQuotesByDate(ctx context.Context, date string) ([]DailyQuote, error) {
+	return collectPages(c.DailyQuotesByDatePages(ctx, date))
+}
+
+func (c *Client) DailyQuotesByDatePages(ctx context.Context, date string) iter.Seq2[[]DailyQuote, error] {
+	params := url.Values{}
+	params.Set("date", date)
+	return fetchPages[DailyQuote](ctx, c, DailyQuotesEndpoint, params, "daily_quotes")
+}
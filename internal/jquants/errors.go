@@ -0,0 +1,69 @@
+package jquants
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// errors.Is で判定するためのエラー
+// API から返るエラーは *APIError で、ステータスコードに応じてこれらに一致する
+var (
+	ErrUnauthorized = errors.New("jquants: unauthorized") // 401/403、リフレッシュトークンの誤り・失効
+	ErrRateLimited  = errors.New("jquants: rate limited") // 429 (再試行しても解消しなかった)
+	ErrNotFound     = errors.New("jquants: not found")    // 404
+	ErrBadRequest   = errors.New("jquants: bad request")  // 400 (日付やコードの指定誤りなど)
+)
+
+// エラーメッセージに含めるレスポンスボディの最大長
+const bodyExcerptLimit = 200
+
+// J-Quants API が 200 以外を返した場合のエラー
+type APIError struct {
+	StatusCode int
+	Endpoint   string // 例: "/fins/statements"
+	Body       string // レスポンスボディの先頭部分
+	Retries    int    // 429 / 5xx による再試行回数
+}
+
+func newAPIError(endpoint string, status int, body []byte, retries int) *APIError {
+	excerpt := strings.TrimSpace(string(body))
+	if len(excerpt) > bodyExcerptLimit {
+		// 日本語のメッセージを文字の途中で切らないよう、文字の先頭まで戻る
+		cut := bodyExcerptLimit
+		for cut > 0 && !utf8.RuneStart(excerpt[cut]) {
+			cut--
+		}
+		excerpt = excerpt[:cut] + "..."
+	}
+	return &APIError{StatusCode: status, Endpoint: endpoint, Body: excerpt, Retries: retries}
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("jquants: %s: status %d", e.Endpoint, e.StatusCode)
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	if e.Retries > 0 {
+		msg += fmt.Sprintf(" (after %d retries)", e.Retries)
+	}
+	return msg
+}
+
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		// auth_refresh はリフレッシュトークンが不正な場合 400 を返す (429 はレート制限でトークンの問題ではない)
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden ||
+			(e.Endpoint == AuthEndpoint && e.StatusCode >= 400 && e.StatusCode < 500 && e.StatusCode != http.StatusTooManyRequests)
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest && e.Endpoint != AuthEndpoint
+	}
+	return false
+}
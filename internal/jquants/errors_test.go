@@ -0,0 +1,29 @@
+package jquants
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestAPIErrorBodyExcerpt(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"short", `{"message": "invalid code"}`, `{"message": "invalid code"}`},
+		{"ascii", strings.Repeat("a", bodyExcerptLimit+1), strings.Repeat("a", bodyExcerptLimit) + "..."},
+		// 3バイトの文字が上限をまたぐ場合は、その文字の前で切る
+		{"multibyte", "a" + strings.Repeat("あ", bodyExcerptLimit/3+1), "a" + strings.Repeat("あ", (bodyExcerptLimit-1)/3) + "..."},
+	}
+	for _, tt := range tests {
+		e := newAPIError(DailyQuotesEndpoint, 400, []byte(tt.body), 0)
+		if e.Body != tt.want {
+			t.Errorf("%s: body = %q, want %q", tt.name, e.Body, tt.want)
+		}
+		if !utf8.ValidString(e.Body) {
+			t.Errorf("%s: body is not valid UTF-8: %q", tt.name, e.Body)
+		}
+	}
+}
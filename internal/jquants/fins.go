@@ -0,0 +1,115 @@
+package jquants
+
+import (
+	"context"
+	"iter"
+	"net/url"
+)
+
+const AnnouncementEndpoint = "/fins/announcement"
+
+// 決算発表予定 (/fins/announcement)
+// J-Quants が返すのは翌営業日に決算発表を予定している銘柄のみ
+type Announcement struct {
+	Date          string `json:"Date"` // 発表予定日 (日付未定の場合は空)
+	Code          string `json:"Code"`
+	CompanyName   string `json:"CompanyName"`
+	FiscalYear    string `json:"FiscalYear"`    // 例: "3月31日"
+	SectorName    string `json:"SectorName"`    // 業種名
+	FiscalQuarter string `json:"FiscalQuarter"` // 例: "第１四半期"
+	Section       string `json:"Section"`       // 市場区分
+}
+
+// 決算発表予定を取得する
+func (c *Client) GetAnnouncements(ctx context.Context) ([]Announcement, error) {
+	return collectPages(c.AnnouncementPages(ctx))
+}
+
+func (c *Client) AnnouncementPages(ctx context.Context) iter.Seq2[[]Announcement, error] {
+	return fetchPages[Announcement](ctx, c, AnnouncementEndpoint, nil, "announcement")
+}
+
+const (
+	DividendEndpoint  = "/fins/dividend"
+	FSDetailsEndpoint = "/fins/fs_details"
+)
+
+// 配当金情報 (/fins/dividend)
+// 金額・率は決定していない場合 "-"、該当しない場合は空文字になるので ParseNumber で変換する
+type Dividend struct {
+	AnnouncementDate          string `json:"AnnouncementDate"`
+	AnnouncementTime          string `json:"AnnouncementTime"`
+	Code                      string `json:"Code"`
+	ReferenceNumber           string `json:"ReferenceNumber"`
+	StatusCode                string `json:"StatusCode"`         // 1: 新規, 2: 訂正, 3: 削除
+	BoardMeetingDate          string `json:"BoardMeetingDate"`   // 取締役会開催日
+	InterimFinalCode          string `json:"InterimFinalCode"`   // 1: 中間, 2: 期末
+	ForecastResultCode        string `json:"ForecastResultCode"` // 1: 決定, 2: 予想
+	InterimFinalTerm          string `json:"InterimFinalTerm"`   // 配当基準日の年月 (例: "2025-03")
+	GrossDividendRate         string `json:"GrossDividendRate"`  // 1株あたり配当金
+	RecordDate                string `json:"RecordDate"`
+	ExDate                    string `json:"ExDate"`
+	ActualRecordDate          string `json:"ActualRecordDate"`
+	PayableDate               string `json:"PayableDate"`
+	CAReferenceNumber         string `json:"CAReferenceNumber"`
+	DistributionAmount        string `json:"DistributionAmount"`
+	RetainedEarnings          string `json:"RetainedEarnings"`
+	DeemedDividend            string `json:"DeemedDividend"`
+	DeemedCapitalGains        string `json:"DeemedCapitalGains"`
+	NetAssetDecreaseRatio     string `json:"NetAssetDecreaseRatio"`
+	CommemorativeSpecialCode  string `json:"CommemorativeSpecialCode"` // 0: 通常, 1: 記念, 2: 特別, 3: 記念・特別
+	CommemorativeDividendRate string `json:"CommemorativeDividendRate"`
+	ExtraordinaryDividendRate string `json:"ExtraordinaryDividendRate"`
+}
+
+const (
+	DividendStatusNew     = "1"
+	DividendStatusRevised = "2"
+	DividendStatusDeleted = "3"
+)
+
+// 財務諸表の詳細 (/fins/fs_details)
+// FinancialStatement は XBRL の勘定科目名 (例: "Cash and deposits", "Total assets") -> 値の文字列
+// 会計基準 (日本基準/IFRS/US GAAP) によって科目名が異なる点に注意
+type FSDetails struct {
+	DisclosedDate      string            `json:"DisclosedDate"`
+	DisclosedTime      string            `json:"DisclosedTime"`
+	LocalCode          string            `json:"LocalCode"`
+	DisclosureNumber   string            `json:"DisclosureNumber"`
+	TypeOfDocument     string            `json:"TypeOfDocument"`
+	FinancialStatement map[string]string `json:"FinancialStatement"`
+}
+
+// keys のうち最初に値が入っている科目を返す (会計基準ごとの科目名の違いを吸収するため)
+func (d FSDetails) Value(keys ...string) *float64 {
+	for _, key := range keys {
+		if v, err := ParseNumber(d.FinancialStatement[key]); err == nil && v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+// 配当金情報を取得する。fromDate / toDate は発表日の範囲
+func (c *Client) GetDividends(ctx context.Context, code string, fromDate string, toDate string) ([]Dividend, error) {
+	return collectPages(c.DividendPages(ctx, code, fromDate, toDate))
+}
+
+func (c *Client) DividendPages(ctx context.Context, code string, fromDate string, toDate string) iter.Seq2[[]Dividend, error] {
+	params := url.Values{}
+	params.Set("code", code)
+	params.Set("from", fromDate)
+	params.Set("to", toDate)
+	return fetchPages[Dividend](ctx, c, DividendEndpoint, params, "dividend")
+}
+
+// 銘柄の財務諸表詳細を全開示分取得する
+func (c *Client) GetFSDetails(ctx context.Context, code string) ([]FSDetails, error) {
+	return collectPages(c.FSDetailsPages(ctx, code))
+}
+
+func (c *Client) FSDetailsPages(ctx context.Context, code string) iter.Seq2[[]FSDetails, error] {
+	params := url.Values{}
+	params.Set("code", code)
+	return fetchPages[FSDetails](ctx, c, FSDetailsEndpoint, params, "fs_details")
+}
@@ -0,0 +1,75 @@
+package jquants
+
+import (
+	"context"
+	"iter"
+	"net/url"
+)
+
+const (
+	IndicesEndpoint = "/indices"
+	TopixEndpoint   = "/indices/topix"
+
+	TopixIndexCode = "0000"
+)
+
+// 指数の四本値 (/indices, /indices/topix)
+// /indices/topix のレスポンスには Code が含まれないので、GetTopix では TopixIndexCode を埋める
+type IndexQuote struct {
+	Date  string  `json:"Date"`
+	Code  string  `json:"Code"`
+	Open  float64 `json:"Open"`
+	High  float64 `json:"High"`
+	Low   float64 `json:"Low"`
+	Close float64 `json:"Close"`
+}
+
+// 指数コードを指定して四本値を取得する
+func (c *Client) GetIndexQuotes(ctx context.Context, code string, fromDate string, toDate string) ([]IndexQuote, error) {
+	return collectPages(c.IndexQuotePages(ctx, code, fromDate, toDate))
+}
+
+func (c *Client) IndexQuotePages(ctx context.Context, code string, fromDate string, toDate string) iter.Seq2[[]IndexQuote, error] {
+	params := url.Values{}
+	params.Set("code", code)
+	params.Set("from", fromDate)
+	params.Set("to", toDate)
+	return fetchPages[IndexQuote](ctx, c, IndicesEndpoint, params, "indices")
+}
+
+// TOPIX の四本値を取得する
+func (c *Client) GetTopix(ctx context.Context, fromDate string, toDate string) ([]IndexQuote, error) {
+	quotes, err := collectPages(c.TopixPages(ctx, fromDate, toDate))
+	if err != nil {
+		return nil, err
+	}
+	for i := range quotes {
+		quotes[i].Code = TopixIndexCode
+	}
+	return quotes, nil
+}
+
+func (c *Client) TopixPages(ctx context.Context, fromDate string, toDate string) iter.Seq2[[]IndexQuote, error] {
+	params := url.Values{}
+	params.Set("from", fromDate)
+	params.Set("to", toDate)
+	return fetchPages[IndexQuote](ctx, c, TopixEndpoint, params, "topix")
+}
+
+// 東証33業種コード → TOPIX業種別指数の指数コード
+// 指数コードは 0040 (水産・農林業) から 0060 (サービス業) まで業種コード順に振られている
+var sector33IndexCodes = map[string]string{
+	"0050": "0040", "1050": "0041", "2050": "0042", "3050": "0043", "3100": "0044",
+	"3150": "0045", "3200": "0046", "3250": "0047", "3300": "0048", "3350": "0049",
+	"3400": "004A", "3450": "004B", "3500": "004C", "3550": "004D", "3600": "004E",
+	"3650": "004F", "3700": "0050", "3750": "0051", "3800": "0052", "4050": "0053",
+	"5050": "0054", "5100": "0055", "5150": "0056", "5200": "0057", "5250": "0058",
+	"6050": "0059", "6100": "005A", "7050": "005B", "7100": "005C", "7150": "005D",
+	"7200": "005E", "8050": "005F", "9050": "0060",
+}
+
+// 33業種コードに対応する業種別指数のコードを返す (ETF などの "9999" は false)
+func Sector33IndexCode(sector33Code string) (string, bool) {
+	code, ok := sector33IndexCodes[sector33Code]
+	return code, ok
+}
@@ -0,0 +1,216 @@
+package jquants
+
+import (
+	"context"
+	"iter"
+	"net/url"
+)
+
+const TradingCalendarEndpoint = "/markets/trading_calendar"
+
+// 休日区分 (HolidayDivision)
+const (
+	HolidayNonBusinessDay        = "0" // 非営業日
+	HolidayBusinessDay           = "1" // 営業日
+	HolidayHalfDay               = "2" // 東証半日立会日
+	HolidayNonBusinessDayHoliday = "3" // 非営業日 (祝日取引あり)
+)
+
+// 取引カレンダーの1日分
+type TradingCalendarDay struct {
+	Date            string `json:"Date"`
+	HolidayDivision string `json:"HolidayDivision"`
+}
+
+// 東証で立会がある日か (半日立会日も含む)
+func (d TradingCalendarDay) IsTradingDay() bool {
+	return d.HolidayDivision == HolidayBusinessDay || d.HolidayDivision == HolidayHalfDay
+}
+
+// 取引カレンダーを取得する。from / to が空なら全期間
+func (c *Client) GetTradingCalendar(ctx context.Context, fromDate string, toDate string) ([]TradingCalendarDay, error) {
+	return collectPages(c.TradingCalendarPages(ctx, fromDate, toDate))
+}
+
+func (c *Client) TradingCalendarPages(ctx context.Context, fromDate string, toDate string) iter.Seq2[[]TradingCalendarDay, error] {
+	params := url.Values{}
+	if fromDate != "" {
+		params.Set("from", fromDate)
+	}
+	if toDate != "" {
+		params.Set("to", toDate)
+	}
+	return fetchPages[TradingCalendarDay](ctx, c, TradingCalendarEndpoint, params, "trading_calendar")
+}
+
+const (
+	WeeklyMarginInterestEndpoint = "/markets/weekly_margin_interest"
+	ShortSellingEndpoint         = "/markets/short_selling"
+)
+
+// 信用取引週末残高 (/markets/weekly_margin_interest)
+// Date は申込日 (通常は週末の金曜日)。残高は株数
+type WeeklyMarginInterest struct {
+	Date                               string  `json:"Date"`
+	Code                               string  `json:"Code"`
+	ShortMarginTradeVolume             float64 `json:"ShortMarginTradeVolume"` // 売残高
+	LongMarginTradeVolume              float64 `json:"LongMarginTradeVolume"`  // 買残高
+	ShortNegotiableMarginTradeVolume   float64 `json:"ShortNegotiableMarginTradeVolume"`
+	LongNegotiableMarginTradeVolume    float64 `json:"LongNegotiableMarginTradeVolume"`
+	ShortStandardizedMarginTradeVolume float64 `json:"ShortStandardizedMarginTradeVolume"`
+	LongStandardizedMarginTradeVolume  float64 `json:"LongStandardizedMarginTradeVolume"`
+	IssueType                          string  `json:"IssueType"` // 1: 信用銘柄, 2: 貸借銘柄, 3: その他
+}
+
+// 信用倍率 (買残 / 売残)。売残が 0 の場合は false
+func (m WeeklyMarginInterest) MarginRatio() (float64, bool) {
+	if m.ShortMarginTradeVolume <= 0 {
+		return 0, false
+	}
+	return m.LongMarginTradeVolume / m.ShortMarginTradeVolume, true
+}
+
+// 業種別空売り比率 (/markets/short_selling)。金額は円
+type ShortSelling struct {
+	Date                                         string  `json:"Date"`
+	Sector33Code                                 string  `json:"Sector33Code"`
+	SellingExcludingShortSellingTurnoverValue    float64 `json:"SellingExcludingShortSellingTurnoverValue"`    // 実注文の売買代金
+	ShortSellingWithRestrictionsTurnoverValue    float64 `json:"ShortSellingWithRestrictionsTurnoverValue"`    // 価格規制ありの空売り
+	ShortSellingWithoutRestrictionsTurnoverValue float64 `json:"ShortSellingWithoutRestrictionsTurnoverValue"` // 価格規制なしの空売り
+}
+
+// 売り注文全体に占める空売りの割合 (%)
+func (s ShortSelling) ShortSellingRatio() (float64, bool) {
+	short := s.ShortSellingWithRestrictionsTurnoverValue + s.ShortSellingWithoutRestrictionsTurnoverValue
+	total := s.SellingExcludingShortSellingTurnoverValue + short
+	if total <= 0 {
+		return 0, false
+	}
+	return short / total * 100, true
+}
+
+// 銘柄の信用取引週末残高を取得する
+func (c *Client) GetWeeklyMarginInterest(ctx context.Context, code string, fromDate string, toDate string) ([]WeeklyMarginInterest, error) {
+	return collectPages(c.WeeklyMarginInterestPages(ctx, code, fromDate, toDate))
+}
+
+func (c *Client) WeeklyMarginInterestPages(ctx context.Context, code string, fromDate string, toDate string) iter.Seq2[[]WeeklyMarginInterest, error] {
+	params := url.Values{}
+	params.Set("code", code)
+	params.Set("from", fromDate)
+	params.Set("to", toDate)
+	return fetchPages[WeeklyMarginInterest](ctx, c, WeeklyMarginInterestEndpoint, params, "weekly_margin_interest")
+}
+
+// 33業種の空売り比率を取得する
+func (c *Client) GetShortSelling(ctx context.Context, sector33Code string, fromDate string, toDate string) ([]ShortSelling, error) {
+	return collectPages(c.ShortSellingPages(ctx, sector33Code, fromDate, toDate))
+}
+
+func (c *Client) ShortSellingPages(ctx context.Context, sector33Code string, fromDate string, toDate string) iter.Seq2[[]ShortSelling, error] {
+	params := url.Values{}
+	params.Set("sector33code", sector33Code)
+	params.Set("from", fromDate)
+	params.Set("to", toDate)
+	return fetchPages[ShortSelling](ctx, c, ShortSellingEndpoint, params, "short_selling")
+}
+
+const TradesSpecEndpoint = "/markets/trades_spec"
+
+// 投資部門別売買状況の市場区分 (Section)
+const (
+	SectionTSEPrime    = "TSEPrime"
+	SectionTSEStandard = "TSEStandard"
+	SectionTSEGrowth   = "TSEGrowth"
+)
+
+// 投資部門別売買状況 (/markets/trades_spec)
+// 週次 (StartDate〜EndDate) の集計で、PublishedDate に公表される。金額は千円単位
+// Balance は 買い - 売り (差引き)
+type TradesSpec struct {
+	PublishedDate string `json:"PublishedDate"`
+	StartDate     string `json:"StartDate"`
+	EndDate       string `json:"EndDate"`
+	Section       string `json:"Section"`
+
+	// 自己計
+	ProprietarySales     float64 `json:"ProprietarySales"`
+	ProprietaryPurchases float64 `json:"ProprietaryPurchases"`
+	ProprietaryTotal     float64 `json:"ProprietaryTotal"`
+	ProprietaryBalance   float64 `json:"ProprietaryBalance"`
+	// 委託計
+	BrokerageSales     float64 `json:"BrokerageSales"`
+	BrokeragePurchases float64 `json:"BrokeragePurchases"`
+	BrokerageTotal     float64 `json:"BrokerageTotal"`
+	BrokerageBalance   float64 `json:"BrokerageBalance"`
+	// 総計
+	TotalSales     float64 `json:"TotalSales"`
+	TotalPurchases float64 `json:"TotalPurchases"`
+	TotalTotal     float64 `json:"TotalTotal"`
+	TotalBalance   float64 `json:"TotalBalance"`
+	// 個人
+	IndividualsSales     float64 `json:"IndividualsSales"`
+	IndividualsPurchases float64 `json:"IndividualsPurchases"`
+	IndividualsTotal     float64 `json:"IndividualsTotal"`
+	IndividualsBalance   float64 `json:"IndividualsBalance"`
+	// 海外投資家
+	ForeignersSales     float64 `json:"ForeignersSales"`
+	ForeignersPurchases float64 `json:"ForeignersPurchases"`
+	ForeignersTotal     float64 `json:"ForeignersTotal"`
+	ForeignersBalance   float64 `json:"ForeignersBalance"`
+	// 証券会社
+	SecuritiesCosSales     float64 `json:"SecuritiesCosSales"`
+	SecuritiesCosPurchases float64 `json:"SecuritiesCosPurchases"`
+	SecuritiesCosTotal     float64 `json:"SecuritiesCosTotal"`
+	SecuritiesCosBalance   float64 `json:"SecuritiesCosBalance"`
+	// 投資信託
+	InvestmentTrustsSales     float64 `json:"InvestmentTrustsSales"`
+	InvestmentTrustsPurchases float64 `json:"InvestmentTrustsPurchases"`
+	InvestmentTrustsTotal     float64 `json:"InvestmentTrustsTotal"`
+	InvestmentTrustsBalance   float64 `json:"InvestmentTrustsBalance"`
+	// 事業法人
+	BusinessCosSales     float64 `json:"BusinessCosSales"`
+	BusinessCosPurchases float64 `json:"BusinessCosPurchases"`
+	BusinessCosTotal     float64 `json:"BusinessCosTotal"`
+	BusinessCosBalance   float64 `json:"BusinessCosBalance"`
+	// その他法人
+	OtherCosSales     float64 `json:"OtherCosSales"`
+	OtherCosPurchases float64 `json:"OtherCosPurchases"`
+	OtherCosTotal     float64 `json:"OtherCosTotal"`
+	OtherCosBalance   float64 `json:"OtherCosBalance"`
+	// 生保・損保
+	InsuranceCosSales     float64 `json:"InsuranceCosSales"`
+	InsuranceCosPurchases float64 `json:"InsuranceCosPurchases"`
+	InsuranceCosTotal     float64 `json:"InsuranceCosTotal"`
+	InsuranceCosBalance   float64 `json:"InsuranceCosBalance"`
+	// 都銀・地銀等
+	CityBKsRegionalBKsEtcSales     float64 `json:"CityBKsRegionalBKsEtcSales"`
+	CityBKsRegionalBKsEtcPurchases float64 `json:"CityBKsRegionalBKsEtcPurchases"`
+	CityBKsRegionalBKsEtcTotal     float64 `json:"CityBKsRegionalBKsEtcTotal"`
+	CityBKsRegionalBKsEtcBalance   float64 `json:"CityBKsRegionalBKsEtcBalance"`
+	// 信託銀行
+	TrustBanksSales     float64 `json:"TrustBanksSales"`
+	TrustBanksPurchases float64 `json:"TrustBanksPurchases"`
+	TrustBanksTotal     float64 `json:"TrustBanksTotal"`
+	TrustBanksBalance   float64 `json:"TrustBanksBalance"`
+	// その他金融機関
+	OtherFinancialInstitutionsSales     float64 `json:"OtherFinancialInstitutionsSales"`
+	OtherFinancialInstitutionsPurchases float64 `json:"OtherFinancialInstitutionsPurchases"`
+	OtherFinancialInstitutionsTotal     float64 `json:"OtherFinancialInstitutionsTotal"`
+	OtherFinancialInstitutionsBalance   float64 `json:"OtherFinancialInstitutionsBalance"`
+}
+
+// 投資部門別売買状況を取得する。fromDate / toDate は公表日の範囲
+func (c *Client) GetTradesSpec(ctx context.Context, section string, fromDate string, toDate string) ([]TradesSpec, error) {
+	return collectPages(c.TradesSpecPages(ctx, section, fromDate, toDate))
+}
+
+func (c *Client) TradesSpecPages(ctx context.Context, section string, fromDate string, toDate string) iter.Seq2[[]TradesSpec, error] {
+	params := url.Values{}
+	if section != "" {
+		params.Set("section", section)
+	}
+	params.Set("from", fromDate)
+	params.Set("to", toDate)
+	return fetchPages[TradesSpec](ctx, c, TradesSpecEndpoint, params, "trades_spec")
+}
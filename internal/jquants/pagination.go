@@ -0,0 +1,112 @@
+package jquants
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"net/url"
+)
+
+// J-Quants のリスト系APIは、レスポンスが大きい場合 pagination_key を返して続きを分割する。
+// fetchPages は pagination_key が空になるまで後続ページを取得し、1ページずつ yield する。
+// dataKey はレスポンスJSON内の配列のキー ("statements", "info", "daily_quotes" など)。
+func fetchPages[T any](ctx context.Context, c *Client, path string, params url.Values, dataKey string) iter.Seq2[[]T, error] {
+	return func(yield func([]T, error) bool) {
+		// 呼び出し元の params を書き換えないようにコピーしてから pagination_key を足していく
+		q := url.Values{}
+		for k, v := range params {
+			q[k] = v
+		}
+
+		for {
+			items, nextKey, err := fetchPage[T](ctx, c, path, q, dataKey)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(items, nil) {
+				return
+			}
+			if nextKey == "" {
+				return
+			}
+			q.Set("pagination_key", nextKey)
+		}
+	}
+}
+
+// 1ページ分を取得し、データ配列と次ページの pagination_key を返す
+func fetchPage[T any](ctx context.Context, c *Client, path string, q url.Values, dataKey string) ([]T, string, error) {
+	body, err := c.getPageBody(ctx, path, q)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// データ配列のキーはエンドポイントごとに異なるので、一旦 RawMessage で受ける
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, "", err
+	}
+
+	var items []T
+	if data, ok := raw[dataKey]; ok {
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, "", err
+		}
+	}
+
+	var nextKey string
+	if key, ok := raw["pagination_key"]; ok {
+		if err := json.Unmarshal(key, &nextKey); err != nil {
+			return nil, "", err
+		}
+	}
+	return items, nextKey, nil
+}
+
+// 1ページ分のレスポンスボディを返す。キャッシュがあればAPIを呼ばない
+func (c *Client) getPageBody(ctx context.Context, path string, q url.Values) ([]byte, error) {
+	key := cacheKey(c.baseURL, path, q)
+	if c.cache != nil {
+		if body, ok := c.cache.Get(key); ok {
+			return body, nil
+		}
+	}
+
+	resp, retries, err := c.getAuthorized(ctx, path, q)
+	if err != nil {
+		if retries > 0 {
+			return nil, fmt.Errorf("%w (after %d retries)", err, retries)
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		return nil, newAPIError(path, resp.StatusCode, body, retries)
+	}
+
+	if c.cache != nil {
+		// キャッシュの書き込み失敗は取得結果には影響させない
+		_ = c.cache.Set(key, body, cacheTTL(path, q))
+	}
+	return body, nil
+}
+
+// 全ページを取得して1つのスライスにまとめる
+func collectPages[T any](pages iter.Seq2[[]T, error]) ([]T, error) {
+	var all []T
+	for page, err := range pages {
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+	}
+	return all, nil
+}
@@ -0,0 +1,54 @@
+package jquants
+
+import (
+	"context"
+	"iter"
+	"net/url"
+)
+
+const PricesAMEndpoint = "/prices/prices_am"
+
+// 前場の四本値 (/prices/prices_am)
+// API が返すのは当日分 (前場終了後に更新) だけなので、過去分は DailyQuote の Morning* を使う
+type MorningQuote struct {
+	Date                 string  `json:"Date"`
+	Code                 string  `json:"Code"`
+	MorningOpen          float64 `json:"MorningOpen"`
+	MorningHigh          float64 `json:"MorningHigh"`
+	MorningLow           float64 `json:"MorningLow"`
+	MorningClose         float64 `json:"MorningClose"`
+	MorningVolume        float64 `json:"MorningVolume"`
+	MorningTurnoverValue float64 `json:"MorningTurnoverValue"`
+}
+
+// 当日の前場の四本値を取得する。code が空なら全銘柄
+func (c *Client) GetPricesAM(ctx context.Context, code string) ([]MorningQuote, error) {
+	return collectPages(c.PricesAMPages(ctx, code))
+}
+
+func (c *Client) PricesAMPages(ctx context.Context, code string) iter.Seq2[[]MorningQuote, error] {
+	params := url.Values{}
+	if code != "" {
+		params.Set("code", code)
+	}
+	return fetchPages[MorningQuote](ctx, c, PricesAMEndpoint, params, "prices_am")
+}
+
+// 日足に含まれる前場の四本値を MorningQuote として取り出す
+// 前場・後場別のデータがない (プランの制限や前場が値付かず) 場合は false
+// 調整済みの値が欲しい場合は先に In(PriceAdjusted) しておくこと
+func (q DailyQuote) MorningSession() (MorningQuote, bool) {
+	if q.MorningOpen <= 0 {
+		return MorningQuote{}, false
+	}
+	return MorningQuote{
+		Date:                 q.Date,
+		Code:                 q.Code,
+		MorningOpen:          q.MorningOpen,
+		MorningHigh:          q.MorningHigh,
+		MorningLow:           q.MorningLow,
+		MorningClose:         q.MorningClose,
+		MorningVolume:        q.MorningVolume,
+		MorningTurnoverValue: q.MorningTurnoverValue,
+	}, true
+}
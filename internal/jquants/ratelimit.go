@@ -0,0 +1,82 @@
+package jquants
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// デフォルトのレート制限 (1秒あたりのリクエスト数とバースト)
+// get_price_trend が数百銘柄分連続で呼ばれても 429 にならない程度に抑える
+const (
+	DefaultRequestsPerSecond = 5.0
+	DefaultBurst             = 5
+)
+
+// トークンバケット方式のレートリミッタ
+// 複数 goroutine から共有して使える
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // 1秒あたりに補充されるトークン数
+	burst  float64 // バケットの容量
+	tokens float64
+	last   time.Time
+}
+
+// ratePerSec <= 0 の場合は nil (制限なし) を返す
+func NewRateLimiter(ratePerSec float64, burst int) *RateLimiter {
+	if ratePerSec <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:   ratePerSec,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// トークンが1つ取れるまで待つ。ctx がキャンセルされたらエラーを返す
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+		l.last = now
+
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		// 1トークン貯まるまでの時間だけ待ってから再確認する
+		wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+
+		if err := sleepContext(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// ctx がキャンセルされるまで d だけ待つ
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
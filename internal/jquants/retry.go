@@ -0,0 +1,150 @@
+package jquants
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// 429 / 5xx を受けた時の再試行方針
+type RetryPolicy struct {
+	MaxRetries int           // 再試行の最大回数 (0 なら再試行しない)
+	BaseDelay  time.Duration // 1回目の待ち時間の上限。以降は倍々に増やす
+	MaxDelay   time.Duration // 待ち時間の上限 (Retry-After もこれで頭打ちにする)
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 4,
+	BaseDelay:  1 * time.Second,
+	MaxDelay:   30 * time.Second,
+}
+
+// レート制限を設定する。ratePerSec <= 0 なら制限なし
+func WithRateLimit(ratePerSec float64, burst int) Option {
+	return func(c *Client) { c.limiter = NewRateLimiter(ratePerSec, burst) }
+}
+
+// 再試行方針を設定する
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) { c.retry = p }
+}
+
+func isRetryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// retries 回目 (0始まり) の再試行までの待ち時間
+// Retry-After があればそれに従い、なければ指数バックオフ + Full Jitter
+func (p RetryPolicy) delay(retries int, retryAfter string) time.Duration {
+	if d, ok := parseRetryAfter(retryAfter); ok {
+		if p.MaxDelay > 0 {
+			d = min(d, p.MaxDelay)
+		}
+		return d
+	}
+
+	backoff := p.BaseDelay << retries
+	if backoff <= 0 || (p.MaxDelay > 0 && backoff > p.MaxDelay) {
+		backoff = p.MaxDelay
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	return rand.N(backoff) + 1
+}
+
+// Retry-After は秒数か HTTP-date のどちらか
+func parseRetryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+// レート制限を守ってリクエストを送る
+func (c *Client) send(ctx context.Context, req *http.Request) (*http.Response, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return c.httpClient.Do(req)
+}
+
+// レート制限を守ってリクエストを送り、429 / 5xx は RetryPolicy に従って待ってから再試行する
+// newReq は試行ごとにリクエストを作り直す。戻り値の int は再試行回数 (エラーメッセージ用)
+// 呼び出し元は 200 以外のステータスも自分で処理し、Body を Close すること
+func (c *Client) sendWithRetry(ctx context.Context, newReq func() (*http.Request, error)) (*http.Response, int, error) {
+	for retries := 0; ; retries++ {
+		req, err := newReq()
+		if err != nil {
+			return nil, retries, err
+		}
+		resp, err := c.send(ctx, req)
+		if err != nil {
+			return nil, retries, err
+		}
+		if !isRetryableStatus(resp.StatusCode) || retries >= c.retry.MaxRetries {
+			return resp, retries, nil
+		}
+
+		wait := c.retry.delay(retries, resp.Header.Get("Retry-After"))
+		drainAndClose(resp)
+		if err := sleepContext(ctx, wait); err != nil {
+			return nil, retries + 1, err
+		}
+	}
+}
+
+// IDToken を付けて GET する
+//   - 401 が返った場合はトークンを更新して1回だけ再試行する
+//   - 429 / 5xx は RetryPolicy に従って待ってから再試行する (sendWithRetry)
+//
+// 戻り値の int は 429 / 5xx による再試行回数 (エラーメッセージ用)
+// 呼び出し元は 200 以外のステータスも自分で処理し、Body を Close すること
+func (c *Client) getAuthorized(ctx context.Context, path string, q url.Values) (*http.Response, int, error) {
+	retries := 0
+	refreshed := false
+	for {
+		token, err := c.token(ctx)
+		if err != nil {
+			return nil, retries, err
+		}
+
+		resp, n, err := c.sendWithRetry(ctx, func() (*http.Request, error) {
+			req, err := c.newRequest(ctx, "GET", path, q)
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+			return req, nil
+		})
+		retries += n
+		if err != nil {
+			return nil, retries, err
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized && !refreshed {
+			// トークンが失効している (サーバー側で無効化された等) ので取り直して再試行
+			drainAndClose(resp)
+			c.invalidateToken(token)
+			refreshed = true
+			continue
+		}
+		return resp, retries, nil
+	}
+}
+
+// コネクションを再利用できるよう Body を読み切ってから閉じる
+func drainAndClose(resp *http.Response) {
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
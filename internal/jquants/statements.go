@@ -0,0 +1,356 @@
+package jquants
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// /fins/statements のレスポンス (決算短信サマリー)
+// J-Quants は数値も日付もすべて文字列で返し、値がない項目は空文字になる
+type FinancialStatement struct {
+	// 基本情報
+	DisclosedDate              string `json:"DisclosedDate"`
+	DisclosedTime              string `json:"DisclosedTime"`
+	LocalCode                  string `json:"LocalCode"`
+	DisclosureNumber           string `json:"DisclosureNumber"`
+	TypeOfDocument             string `json:"TypeOfDocument"`
+	TypeOfCurrentPeriod        string `json:"TypeOfCurrentPeriod"`
+	CurrentPeriodStartDate     string `json:"CurrentPeriodStartDate"`
+	CurrentPeriodEndDate       string `json:"CurrentPeriodEndDate"`
+	CurrentFiscalYearStartDate string `json:"CurrentFiscalYearStartDate"`
+	CurrentFiscalYearEndDate   string `json:"CurrentFiscalYearEndDate"`
+	NextFiscalYearStartDate    string `json:"NextFiscalYearStartDate"`
+	NextFiscalYearEndDate      string `json:"NextFiscalYearEndDate"`
+
+	// 実績
+	NetSales                         string `json:"NetSales"`
+	OperatingProfit                  string `json:"OperatingProfit"`
+	OrdinaryProfit                   string `json:"OrdinaryProfit"`
+	Profit                           string `json:"Profit"`
+	EarningsPerShare                 string `json:"EarningsPerShare"`
+	DilutedEarningsPerShare          string `json:"DilutedEarningsPerShare"`
+	TotalAssets                      string `json:"TotalAssets"`
+	Equity                           string `json:"Equity"`
+	EquityToAssetRatio               string `json:"EquityToAssetRatio"`
+	BookValuePerShare                string `json:"BookValuePerShare"`
+	CashFlowsFromOperatingActivities string `json:"CashFlowsFromOperatingActivities"`
+	CashFlowsFromInvestingActivities string `json:"CashFlowsFromInvestingActivities"`
+	CashFlowsFromFinancingActivities string `json:"CashFlowsFromFinancingActivities"`
+	CashAndEquivalents               string `json:"CashAndEquivalents"`
+
+	// 配当実績
+	ResultDividendPerShare1stQuarter    string `json:"ResultDividendPerShare1stQuarter"`
+	ResultDividendPerShare2ndQuarter    string `json:"ResultDividendPerShare2ndQuarter"`
+	ResultDividendPerShare3rdQuarter    string `json:"ResultDividendPerShare3rdQuarter"`
+	ResultDividendPerShareFiscalYearEnd string `json:"ResultDividendPerShareFiscalYearEnd"`
+	ResultDividendPerShareAnnual        string `json:"ResultDividendPerShareAnnual"`
+	DistributionsPerUnitREIT            string `json:"DistributionsPerUnit(REIT)"`
+	ResultTotalDividendPaidAnnual       string `json:"ResultTotalDividendPaidAnnual"`
+	ResultPayoutRatioAnnual             string `json:"ResultPayoutRatioAnnual"`
+
+	// 今期配当予想
+	ForecastDividendPerShare1stQuarter    string `json:"ForecastDividendPerShare1stQuarter"`
+	ForecastDividendPerShare2ndQuarter    string `json:"ForecastDividendPerShare2ndQuarter"`
+	ForecastDividendPerShare3rdQuarter    string `json:"ForecastDividendPerShare3rdQuarter"`
+	ForecastDividendPerShareFiscalYearEnd string `json:"ForecastDividendPerShareFiscalYearEnd"`
+	ForecastDividendPerShareAnnual        string `json:"ForecastDividendPerShareAnnual"`
+	ForecastDistributionsPerUnitREIT      string `json:"ForecastDistributionsPerUnit(REIT)"`
+	ForecastTotalDividendPaidAnnual       string `json:"ForecastTotalDividendPaidAnnual"`
+	ForecastPayoutRatioAnnual             string `json:"ForecastPayoutRatioAnnual"`
+
+	// 来期配当予想
+	NextYearForecastDividendPerShare1stQuarter    string `json:"NextYearForecastDividendPerShare1stQuarter"`
+	NextYearForecastDividendPerShare2ndQuarter    string `json:"NextYearForecastDividendPerShare2ndQuarter"`
+	NextYearForecastDividendPerShare3rdQuarter    string `json:"NextYearForecastDividendPerShare3rdQuarter"`
+	NextYearForecastDividendPerShareFiscalYearEnd string `json:"NextYearForecastDividendPerShareFiscalYearEnd"`
+	NextYearForecastDividendPerShareAnnual        string `json:"NextYearForecastDividendPerShareAnnual"`
+	NextYearForecastDistributionsPerUnitREIT      string `json:"NextYearForecastDistributionsPerUnit(REIT)"`
+	NextYearForecastPayoutRatioAnnual             string `json:"NextYearForecastPayoutRatioAnnual"`
+
+	// 今期予想 (第2四半期累計)
+	ForecastNetSales2ndQuarter         string `json:"ForecastNetSales2ndQuarter"`
+	ForecastOperatingProfit2ndQuarter  string `json:"ForecastOperatingProfit2ndQuarter"`
+	ForecastOrdinaryProfit2ndQuarter   string `json:"ForecastOrdinaryProfit2ndQuarter"`
+	ForecastProfit2ndQuarter           string `json:"ForecastProfit2ndQuarter"`
+	ForecastEarningsPerShare2ndQuarter string `json:"ForecastEarningsPerShare2ndQuarter"`
+
+	// 来期予想 (第2四半期累計)
+	NextYearForecastNetSales2ndQuarter         string `json:"NextYearForecastNetSales2ndQuarter"`
+	NextYearForecastOperatingProfit2ndQuarter  string `json:"NextYearForecastOperatingProfit2ndQuarter"`
+	NextYearForecastOrdinaryProfit2ndQuarter   string `json:"NextYearForecastOrdinaryProfit2ndQuarter"`
+	NextYearForecastProfit2ndQuarter           string `json:"NextYearForecastProfit2ndQuarter"`
+	NextYearForecastEarningsPerShare2ndQuarter string `json:"NextYearForecastEarningsPerShare2ndQuarter"`
+
+	// 今期予想
+	ForecastNetSales         string `json:"ForecastNetSales"`
+	ForecastOperatingProfit  string `json:"ForecastOperatingProfit"`
+	ForecastOrdinaryProfit   string `json:"ForecastOrdinaryProfit"`
+	ForecastProfit           string `json:"ForecastProfit"`
+	ForecastEarningsPerShare string `json:"ForecastEarningsPerShare"`
+
+	// 来期予想
+	NextYearForecastNetSales         string `json:"NextYearForecastNetSales"`
+	NextYearForecastOperatingProfit  string `json:"NextYearForecastOperatingProfit"`
+	NextYearForecastOrdinaryProfit   string `json:"NextYearForecastOrdinaryProfit"`
+	NextYearForecastProfit           string `json:"NextYearForecastProfit"`
+	NextYearForecastEarningsPerShare string `json:"NextYearForecastEarningsPerShare"`
+
+	// 会計方針の変更など ("true" / "false")
+	MaterialChangesInSubsidiaries                            string `json:"MaterialChangesInSubsidiaries"`
+	SignificantChangesInTheScopeOfConsolidation              string `json:"SignificantChangesInTheScopeOfConsolidation"`
+	ChangesBasedOnRevisionsOfAccountingStandard              string `json:"ChangesBasedOnRevisionsOfAccountingStandard"`
+	ChangesOtherThanOnesBasedOnRevisionsOfAccountingStandard string `json:"ChangesOtherThanOnesBasedOnRevisionsOfAccountingStandard"`
+	ChangesInAccountingEstimates                             string `json:"ChangesInAccountingEstimates"`
+	RetrospectiveRestatement                                 string `json:"RetrospectiveRestatement"`
+
+	// 株式数
+	NumberOfIssuedAndOutstandingSharesAtTheEndOfFiscalYearIncludingTreasuryStock string `json:"NumberOfIssuedAndOutstandingSharesAtTheEndOfFiscalYearIncludingTreasuryStock"`
+	NumberOfTreasuryStockAtTheEndOfFiscalYear                                    string `json:"NumberOfTreasuryStockAtTheEndOfFiscalYear"`
+	AverageNumberOfShares                                                        string `json:"AverageNumberOfShares"`
+
+	// 個別 実績
+	NonConsolidatedNetSales           string `json:"NonConsolidatedNetSales"`
+	NonConsolidatedOperatingProfit    string `json:"NonConsolidatedOperatingProfit"`
+	NonConsolidatedOrdinaryProfit     string `json:"NonConsolidatedOrdinaryProfit"`
+	NonConsolidatedProfit             string `json:"NonConsolidatedProfit"`
+	NonConsolidatedEarningsPerShare   string `json:"NonConsolidatedEarningsPerShare"`
+	NonConsolidatedTotalAssets        string `json:"NonConsolidatedTotalAssets"`
+	NonConsolidatedEquity             string `json:"NonConsolidatedEquity"`
+	NonConsolidatedEquityToAssetRatio string `json:"NonConsolidatedEquityToAssetRatio"`
+	NonConsolidatedBookValuePerShare  string `json:"NonConsolidatedBookValuePerShare"`
+
+	// 個別 今期予想 (第2四半期累計)
+	ForecastNonConsolidatedNetSales2ndQuarter         string `json:"ForecastNonConsolidatedNetSales2ndQuarter"`
+	ForecastNonConsolidatedOperatingProfit2ndQuarter  string `json:"ForecastNonConsolidatedOperatingProfit2ndQuarter"`
+	ForecastNonConsolidatedOrdinaryProfit2ndQuarter   string `json:"ForecastNonConsolidatedOrdinaryProfit2ndQuarter"`
+	ForecastNonConsolidatedProfit2ndQuarter           string `json:"ForecastNonConsolidatedProfit2ndQuarter"`
+	ForecastNonConsolidatedEarningsPerShare2ndQuarter string `json:"ForecastNonConsolidatedEarningsPerShare2ndQuarter"`
+
+	// 個別 来期予想 (第2四半期累計)
+	NextYearForecastNonConsolidatedNetSales2ndQuarter         string `json:"NextYearForecastNonConsolidatedNetSales2ndQuarter"`
+	NextYearForecastNonConsolidatedOperatingProfit2ndQuarter  string `json:"NextYearForecastNonConsolidatedOperatingProfit2ndQuarter"`
+	NextYearForecastNonConsolidatedOrdinaryProfit2ndQuarter   string `json:"NextYearForecastNonConsolidatedOrdinaryProfit2ndQuarter"`
+	NextYearForecastNonConsolidatedProfit2ndQuarter           string `json:"NextYearForecastNonConsolidatedProfit2ndQuarter"`
+	NextYearForecastNonConsolidatedEarningsPerShare2ndQuarter string `json:"NextYearForecastNonConsolidatedEarningsPerShare2ndQuarter"`
+
+	// 個別 今期予想
+	ForecastNonConsolidatedNetSales         string `json:"ForecastNonConsolidatedNetSales"`
+	ForecastNonConsolidatedOperatingProfit  string `json:"ForecastNonConsolidatedOperatingProfit"`
+	ForecastNonConsolidatedOrdinaryProfit   string `json:"ForecastNonConsolidatedOrdinaryProfit"`
+	ForecastNonConsolidatedProfit           string `json:"ForecastNonConsolidatedProfit"`
+	ForecastNonConsolidatedEarningsPerShare string `json:"ForecastNonConsolidatedEarningsPerShare"`
+
+	// 個別 来期予想
+	NextYearForecastNonConsolidatedNetSales         string `json:"NextYearForecastNonConsolidatedNetSales"`
+	NextYearForecastNonConsolidatedOperatingProfit  string `json:"NextYearForecastNonConsolidatedOperatingProfit"`
+	NextYearForecastNonConsolidatedOrdinaryProfit   string `json:"NextYearForecastNonConsolidatedOrdinaryProfit"`
+	NextYearForecastNonConsolidatedProfit           string `json:"NextYearForecastNonConsolidatedProfit"`
+	NextYearForecastNonConsolidatedEarningsPerShare string `json:"NextYearForecastNonConsolidatedEarningsPerShare"`
+}
+
+// FinancialStatement の数値・日付を型付きに変換したもの
+// 数値項目は値がない場合 nil になる (0 と区別するため)
+// フィールド名は FinancialStatement と同じにしてあり、Parse はフィールド名で対応付ける
+type ParsedStatement struct {
+	// 基本情報
+	DisclosedDate              time.Time
+	DisclosedTime              string
+	LocalCode                  string
+	DisclosureNumber           string
+	TypeOfDocument             string
+	TypeOfCurrentPeriod        string
+	CurrentPeriodStartDate     time.Time
+	CurrentPeriodEndDate       time.Time
+	CurrentFiscalYearStartDate time.Time
+	CurrentFiscalYearEndDate   time.Time
+	NextFiscalYearStartDate    time.Time
+	NextFiscalYearEndDate      time.Time
+
+	// 実績
+	NetSales                         *float64
+	OperatingProfit                  *float64
+	OrdinaryProfit                   *float64
+	Profit                           *float64
+	EarningsPerShare                 *float64
+	DilutedEarningsPerShare          *float64
+	TotalAssets                      *float64
+	Equity                           *float64
+	EquityToAssetRatio               *float64
+	BookValuePerShare                *float64
+	CashFlowsFromOperatingActivities *float64
+	CashFlowsFromInvestingActivities *float64
+	CashFlowsFromFinancingActivities *float64
+	CashAndEquivalents               *float64
+
+	// 配当実績
+	ResultDividendPerShare1stQuarter    *float64
+	ResultDividendPerShare2ndQuarter    *float64
+	ResultDividendPerShare3rdQuarter    *float64
+	ResultDividendPerShareFiscalYearEnd *float64
+	ResultDividendPerShareAnnual        *float64
+	DistributionsPerUnitREIT            *float64
+	ResultTotalDividendPaidAnnual       *float64
+	ResultPayoutRatioAnnual             *float64
+
+	// 今期配当予想
+	ForecastDividendPerShare1stQuarter    *float64
+	ForecastDividendPerShare2ndQuarter    *float64
+	ForecastDividendPerShare3rdQuarter    *float64
+	ForecastDividendPerShareFiscalYearEnd *float64
+	ForecastDividendPerShareAnnual        *float64
+	ForecastDistributionsPerUnitREIT      *float64
+	ForecastTotalDividendPaidAnnual       *float64
+	ForecastPayoutRatioAnnual             *float64
+
+	// 来期配当予想
+	NextYearForecastDividendPerShare1stQuarter    *float64
+	NextYearForecastDividendPerShare2ndQuarter    *float64
+	NextYearForecastDividendPerShare3rdQuarter    *float64
+	NextYearForecastDividendPerShareFiscalYearEnd *float64
+	NextYearForecastDividendPerShareAnnual        *float64
+	NextYearForecastDistributionsPerUnitREIT      *float64
+	NextYearForecastPayoutRatioAnnual             *float64
+
+	// 今期予想 (第2四半期累計)
+	ForecastNetSales2ndQuarter         *float64
+	ForecastOperatingProfit2ndQuarter  *float64
+	ForecastOrdinaryProfit2ndQuarter   *float64
+	ForecastProfit2ndQuarter           *float64
+	ForecastEarningsPerShare2ndQuarter *float64
+
+	// 来期予想 (第2四半期累計)
+	NextYearForecastNetSales2ndQuarter         *float64
+	NextYearForecastOperatingProfit2ndQuarter  *float64
+	NextYearForecastOrdinaryProfit2ndQuarter   *float64
+	NextYearForecastProfit2ndQuarter           *float64
+	NextYearForecastEarningsPerShare2ndQuarter *float64
+
+	// 今期予想
+	ForecastNetSales         *float64
+	ForecastOperatingProfit  *float64
+	ForecastOrdinaryProfit   *float64
+	ForecastProfit           *float64
+	ForecastEarningsPerShare *float64
+
+	// 来期予想
+	NextYearForecastNetSales         *float64
+	NextYearForecastOperatingProfit  *float64
+	NextYearForecastOrdinaryProfit   *float64
+	NextYearForecastProfit           *float64
+	NextYearForecastEarningsPerShare *float64
+
+	// 会計方針の変更など ("true" / "false")
+	MaterialChangesInSubsidiaries                            string
+	SignificantChangesInTheScopeOfConsolidation              string
+	ChangesBasedOnRevisionsOfAccountingStandard              string
+	ChangesOtherThanOnesBasedOnRevisionsOfAccountingStandard string
+	ChangesInAccountingEstimates                             string
+	RetrospectiveRestatement                                 string
+
+	// 株式数
+	NumberOfIssuedAndOutstandingSharesAtTheEndOfFiscalYearIncludingTreasuryStock *float64
+	NumberOfTreasuryStockAtTheEndOfFiscalYear                                    *float64
+	AverageNumberOfShares                                                        *float64
+
+	// 個別 実績
+	NonConsolidatedNetSales           *float64
+	NonConsolidatedOperatingProfit    *float64
+	NonConsolidatedOrdinaryProfit     *float64
+	NonConsolidatedProfit             *float64
+	NonConsolidatedEarningsPerShare   *float64
+	NonConsolidatedTotalAssets        *float64
+	NonConsolidatedEquity             *float64
+	NonConsolidatedEquityToAssetRatio *float64
+	NonConsolidatedBookValuePerShare  *float64
+
+	// 個別 今期予想 (第2四半期累計)
+	ForecastNonConsolidatedNetSales2ndQuarter         *float64
+	ForecastNonConsolidatedOperatingProfit2ndQuarter  *float64
+	ForecastNonConsolidatedOrdinaryProfit2ndQuarter   *float64
+	ForecastNonConsolidatedProfit2ndQuarter           *float64
+	ForecastNonConsolidatedEarningsPerShare2ndQuarter *float64
+
+	// 個別 来期予想 (第2四半期累計)
+	NextYearForecastNonConsolidatedNetSales2ndQuarter         *float64
+	NextYearForecastNonConsolidatedOperatingProfit2ndQuarter  *float64
+	NextYearForecastNonConsolidatedOrdinaryProfit2ndQuarter   *float64
+	NextYearForecastNonConsolidatedProfit2ndQuarter           *float64
+	NextYearForecastNonConsolidatedEarningsPerShare2ndQuarter *float64
+
+	// 個別 今期予想
+	ForecastNonConsolidatedNetSales         *float64
+	ForecastNonConsolidatedOperatingProfit  *float64
+	ForecastNonConsolidatedOrdinaryProfit   *float64
+	ForecastNonConsolidatedProfit           *float64
+	ForecastNonConsolidatedEarningsPerShare *float64
+
+	// 個別 来期予想
+	NextYearForecastNonConsolidatedNetSales         *float64
+	NextYearForecastNonConsolidatedOperatingProfit  *float64
+	NextYearForecastNonConsolidatedOrdinaryProfit   *float64
+	NextYearForecastNonConsolidatedProfit           *float64
+	NextYearForecastNonConsolidatedEarningsPerShare *float64
+
+	// 変換できなかった項目 (値は nil / ゼロ値のまま)
+	Warnings []string
+}
+
+// 数値・日付項目を変換した ParsedStatement を返す
+// 変換できない項目があっても他の項目は変換し、Warnings に記録する
+// 開示日が読めないものだけはどの時点の決算か分からないのでエラーにする
+func (s FinancialStatement) Parse() (*ParsedStatement, error) {
+	var p ParsedStatement
+	src := reflect.ValueOf(s)
+	dst := reflect.ValueOf(&p).Elem()
+	dstType := dst.Type()
+	for i := 0; i < dstType.NumField(); i++ {
+		name := dstType.Field(i).Name
+		srcField := src.FieldByName(name)
+		if !srcField.IsValid() {
+			continue
+		}
+		raw := srcField.String()
+		field := dst.Field(i)
+		switch field.Interface().(type) {
+		case *float64:
+			v, err := ParseNumber(raw)
+			if err != nil {
+				p.Warnings = append(p.Warnings, fmt.Sprintf("%s: %v", name, err))
+				continue
+			}
+			field.Set(reflect.ValueOf(v))
+		case time.Time:
+			if raw == "" {
+				continue
+			}
+			t, ok := parseAPIDate(raw)
+			if !ok {
+				p.Warnings = append(p.Warnings, fmt.Sprintf("%s: invalid date %q", name, raw))
+				continue
+			}
+			field.Set(reflect.ValueOf(t))
+		default:
+			field.SetString(raw)
+		}
+	}
+	if p.DisclosedDate.IsZero() {
+		return nil, fmt.Errorf("%s DisclosedDate: invalid date %q", s.LocalCode, s.DisclosedDate)
+	}
+	return &p, nil
+}
+
+// J-Quants の数値文字列を変換する。空文字・"-" は値なしとして nil を返す
+func ParseNumber(s string) (*float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "-" {
+		return nil, nil
+	}
+	v, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", ""), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q", s)
+	}
+	return &v, nil
+}
@@ -0,0 +1,43 @@
+package jquants
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseKeepsOtherFieldsWhenOneIsMalformed(t *testing.T) {
+	s := FinancialStatement{
+		DisclosedDate:        "2025-07-18",
+		LocalCode:            "72030",
+		NetSales:             "1,2x",
+		OperatingProfit:      "100",
+		CurrentPeriodEndDate: "not-a-date",
+	}
+	p, err := s.Parse()
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if p.NetSales != nil {
+		t.Errorf("NetSales = %v, want nil", *p.NetSales)
+	}
+	if p.OperatingProfit == nil || *p.OperatingProfit != 100 {
+		t.Errorf("OperatingProfit = %v, want 100", p.OperatingProfit)
+	}
+	if !p.CurrentPeriodEndDate.IsZero() {
+		t.Errorf("CurrentPeriodEndDate = %v, want zero", p.CurrentPeriodEndDate)
+	}
+	if p.LocalCode != "72030" {
+		t.Errorf("LocalCode = %q, want 72030", p.LocalCode)
+	}
+	warnings := strings.Join(p.Warnings, "\n")
+	if len(p.Warnings) != 2 || !strings.Contains(warnings, "NetSales") || !strings.Contains(warnings, "CurrentPeriodEndDate") {
+		t.Errorf("Warnings = %q, want NetSales and CurrentPeriodEndDate", p.Warnings)
+	}
+}
+
+func TestParseRejectsInvalidDisclosedDate(t *testing.T) {
+	s := FinancialStatement{DisclosedDate: "2025/13/40", LocalCode: "72030", NetSales: "100"}
+	if _, err := s.Parse(); err == nil {
+		t.Error("Parse succeeded, want error for an unreadable DisclosedDate")
+	}
+}
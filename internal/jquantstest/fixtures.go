@@ -0,0 +1,96 @@
+// Package jquantstest は J-Quants API の偽サーバーを提供する。
+// ネットワークやリフレッシュトークンなしで jquants.Client やそれを使うツールを動かすためのもの。
+// 同梱の fixtures/*.json は実際のレスポンス形式を模した架空のデータ。
+package jquantstest
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+)
+
+//go:embed fixtures/*.json
+var defaultFixtures embed.FS
+
+// 偽サーバーが返すデータ
+// 各スライスは J-Quants のレスポンスそのままの形式で fixtures/*.json から読み込む
+type Fixtures struct {
+	Statements      []jquants.FinancialStatement
+	ListedInfo      []jquants.ListedInfo
+	DailyQuotes     []jquants.DailyQuote
+	TradingCalendar []jquants.TradingCalendarDay
+	Announcements   []jquants.Announcement
+	Topix           []jquants.IndexQuote
+	Indices         []jquants.IndexQuote
+	MarginInterest  []jquants.WeeklyMarginInterest
+	ShortSelling    []jquants.ShortSelling
+	Dividends       []jquants.Dividend
+	FSDetails       []jquants.FSDetails
+	TradesSpec      []jquants.TradesSpec
+	PricesAM        []jquants.MorningQuote
+}
+
+// fixture ファイル名とレスポンスJSON内の配列のキー
+var fixtureFiles = []struct {
+	name, key string
+	target    func(*Fixtures) any
+}{
+	{"statements.json", "statements", func(f *Fixtures) any { return &f.Statements }},
+	{"listed_info.json", "info", func(f *Fixtures) any { return &f.ListedInfo }},
+	{"daily_quotes.json", "daily_quotes", func(f *Fixtures) any { return &f.DailyQuotes }},
+	{"trading_calendar.json", "trading_calendar", func(f *Fixtures) any { return &f.TradingCalendar }},
+	{"announcement.json", "announcement", func(f *Fixtures) any { return &f.Announcements }},
+	{"topix.json", "topix", func(f *Fixtures) any { return &f.Topix }},
+	{"indices.json", "indices", func(f *Fixtures) any { return &f.Indices }},
+	{"weekly_margin_interest.json", "weekly_margin_interest", func(f *Fixtures) any { return &f.MarginInterest }},
+	{"short_selling.json", "short_selling", func(f *Fixtures) any { return &f.ShortSelling }},
+	{"dividend.json", "dividend", func(f *Fixtures) any { return &f.Dividends }},
+	{"fs_details.json", "fs_details", func(f *Fixtures) any { return &f.FSDetails }},
+	{"trades_spec.json", "trades_spec", func(f *Fixtures) any { return &f.TradesSpec }},
+	{"prices_am.json", "prices_am", func(f *Fixtures) any { return &f.PricesAM }},
+}
+
+// fsys 直下の fixture ファイルを読み込む。存在しないファイルは空データとして扱う
+func LoadFixtures(fsys fs.FS) (*Fixtures, error) {
+	fx := &Fixtures{}
+	for _, ff := range fixtureFiles {
+		data, err := fs.ReadFile(fsys, ff.name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("%s: %w", ff.name, err)
+		}
+		if items, ok := raw[ff.key]; ok {
+			if err := json.Unmarshal(items, ff.target(fx)); err != nil {
+				return nil, fmt.Errorf("%s: %w", ff.name, err)
+			}
+		}
+	}
+	return fx, nil
+}
+
+// パッケージに同梱しているサンプルデータ
+// 2025-06-02〜2025-07-31 の5銘柄分 (72030 の同業比較用に 72670・72010 を含む) と TOPIX・各銘柄の業種別指数。
+// 39990 は 2025-07-01 に1:2の株式分割と、グロースからスタンダードへの市場区分変更があり、2025-07-18 の決算は昼 (12:00) に開示されている。
+// 前場は 2025-07-31 を「当日」として返す
+func DefaultFixtures() *Fixtures {
+	sub, err := fs.Sub(defaultFixtures, "fixtures")
+	if err != nil {
+		panic(err)
+	}
+	fx, err := LoadFixtures(sub)
+	if err != nil {
+		panic(fmt.Sprintf("jquantstest: broken embedded fixtures: %v", err))
+	}
+	return fx
+}
@@ -0,0 +1,170 @@
+package jquantstest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/marketdata"
+)
+
+// Fixtures はサーバーを立てずにそのまま市場データの取得元 (marketdata.MarketData) としても使える
+// 絞り込みは偽サーバーと同じだが、サンプルデータにない銘柄・期間は (実際の API を真似た偽サーバーのように空の結果ではなく)
+// MarketData の約束どおり jquants.ErrNotFound を返す
+
+var (
+	_ marketdata.MarketData           = (*Fixtures)(nil)
+	_ marketdata.StatementHistoryData = (*Fixtures)(nil)
+	_ marketdata.IndexData            = (*Fixtures)(nil)
+	_ marketdata.MarginData           = (*Fixtures)(nil)
+	_ marketdata.DividendData         = (*Fixtures)(nil)
+	_ marketdata.TradesSpecData       = (*Fixtures)(nil)
+	_ marketdata.MorningSessionData   = (*Fixtures)(nil)
+	_ marketdata.AnnouncementData     = (*Fixtures)(nil)
+)
+
+func (fx *Fixtures) GetStatementsContext(ctx context.Context, targetDate string) ([]jquants.FinancialStatement, error) {
+	statements := filter(fx.Statements, func(s jquants.FinancialStatement) bool {
+		return matchDate(targetDate, s.DisclosedDate)
+	})
+	return found(fx, statements, "", targetDate, targetDate, "statements")
+}
+
+func (fx *Fixtures) GetStatementsByCode(ctx context.Context, code string) ([]jquants.FinancialStatement, error) {
+	statements := filter(fx.Statements, func(s jquants.FinancialStatement) bool {
+		return matchCode(code, s.LocalCode)
+	})
+	return found(fx, statements, code, "", "", "statements")
+}
+
+func (fx *Fixtures) GetDailyQuotesWithMode(ctx context.Context, code string, fromDate string, toDate string, mode jquants.PriceMode) ([]jquants.DailyQuote, error) {
+	quotes := filter(fx.DailyQuotes, func(d jquants.DailyQuote) bool {
+		return matchCode(code, d.Code) && between(d.Date, fromDate, toDate)
+	})
+	if _, err := found(fx, quotes, code, fromDate, toDate, "daily quotes"); err != nil {
+		return nil, err
+	}
+	return jquants.ApplyPriceMode(quotes, mode), nil
+}
+
+func (fx *Fixtures) GetListedInfo(ctx context.Context, code string, date string) ([]jquants.ListedInfo, error) {
+	infos := filter(listedInfoAsOf(fx.ListedInfo, normalizeDate(date)), func(i jquants.ListedInfo) bool {
+		return matchCode(code, i.Code)
+	})
+	return found(fx, infos, code, date, date, "listed info")
+}
+
+func (fx *Fixtures) GetCompanyInfo(ctx context.Context, code string) (*jquants.ListedInfo, error) {
+	infos, _ := fx.GetListedInfo(ctx, code, "")
+	if len(infos) == 0 {
+		return nil, fmt.Errorf("listed info for %s: %w", code, jquants.ErrNotFound)
+	}
+	return &infos[0], nil
+}
+
+func (fx *Fixtures) GetTradingCalendar(ctx context.Context, fromDate string, toDate string) ([]jquants.TradingCalendarDay, error) {
+	days := filter(fx.TradingCalendar, func(d jquants.TradingCalendarDay) bool {
+		return between(d.Date, fromDate, toDate)
+	})
+	return found(fx, days, "", fromDate, toDate, "trading calendar")
+}
+
+func (fx *Fixtures) GetTopix(ctx context.Context, fromDate string, toDate string) ([]jquants.IndexQuote, error) {
+	quotes := filter(fx.Topix, func(d jquants.IndexQuote) bool {
+		return between(d.Date, fromDate, toDate)
+	})
+	return found(fx, quotes, "", fromDate, toDate, "TOPIX")
+}
+
+func (fx *Fixtures) GetIndexQuotes(ctx context.Context, code string, fromDate string, toDate string) ([]jquants.IndexQuote, error) {
+	quotes := filter(fx.Indices, func(d jquants.IndexQuote) bool {
+		return matchCode(code, d.Code) && between(d.Date, fromDate, toDate)
+	})
+	if len(quotes) == 0 && !slices.ContainsFunc(fx.Indices, func(d jquants.IndexQuote) bool { return matchCode(code, d.Code) }) {
+		return nil, fmt.Errorf("index %s not in fixtures: %w", code, jquants.ErrNotFound)
+	}
+	return found(fx, quotes, "", fromDate, toDate, "index quotes")
+}
+
+func (fx *Fixtures) GetWeeklyMarginInterest(ctx context.Context, code string, fromDate string, toDate string) ([]jquants.WeeklyMarginInterest, error) {
+	margin := filter(fx.MarginInterest, func(m jquants.WeeklyMarginInterest) bool {
+		return matchCode(code, m.Code) && between(m.Date, fromDate, toDate)
+	})
+	return found(fx, margin, code, fromDate, toDate, "weekly margin interest")
+}
+
+func (fx *Fixtures) GetShortSelling(ctx context.Context, sector33Code string, fromDate string, toDate string) ([]jquants.ShortSelling, error) {
+	shorts := filter(fx.ShortSelling, func(s jquants.ShortSelling) bool {
+		return (sector33Code == "" || sector33Code == s.Sector33Code) && between(s.Date, fromDate, toDate)
+	})
+	return found(fx, shorts, "", fromDate, toDate, "short selling")
+}
+
+func (fx *Fixtures) GetDividends(ctx context.Context, code string, fromDate string, toDate string) ([]jquants.Dividend, error) {
+	dividends := filter(fx.Dividends, func(d jquants.Dividend) bool {
+		return matchCode(code, d.Code) && between(d.AnnouncementDate, fromDate, toDate)
+	})
+	return found(fx, dividends, code, fromDate, toDate, "dividends")
+}
+
+func (fx *Fixtures) GetFSDetails(ctx context.Context, code string) ([]jquants.FSDetails, error) {
+	details := filter(fx.FSDetails, func(d jquants.FSDetails) bool {
+		return matchCode(code, d.LocalCode)
+	})
+	return found(fx, details, code, "", "", "fs details")
+}
+
+func (fx *Fixtures) GetTradesSpec(ctx context.Context, section string, fromDate string, toDate string) ([]jquants.TradesSpec, error) {
+	specs := filter(fx.TradesSpec, func(t jquants.TradesSpec) bool {
+		return (section == "" || section == t.Section) && between(t.PublishedDate, fromDate, toDate)
+	})
+	return found(fx, specs, "", fromDate, toDate, "trades spec")
+}
+
+func (fx *Fixtures) GetPricesAM(ctx context.Context, code string) ([]jquants.MorningQuote, error) {
+	quotes := filter(fx.PricesAM, func(m jquants.MorningQuote) bool {
+		return matchCode(code, m.Code)
+	})
+	return found(fx, quotes, code, "", "", "prices am")
+}
+
+func (fx *Fixtures) GetAnnouncements(ctx context.Context) ([]jquants.Announcement, error) {
+	return fx.Announcements, nil
+}
+
+// 結果が空で、銘柄がサンプルデータになければ (上場銘柄情報にない)、または期間がサンプルデータの範囲 (取引カレンダー) の外なら
+// jquants.ErrNotFound にする。範囲内で空なのは休場日などの正しい結果なのでそのまま返す
+func found[T any](fx *Fixtures, items []T, code, from, to string, what string) ([]T, error) {
+	if len(items) > 0 {
+		return items, nil
+	}
+	if code != "" && !slices.ContainsFunc(fx.ListedInfo, func(i jquants.ListedInfo) bool { return matchCode(code, i.Code) }) {
+		return nil, fmt.Errorf("%s of %s not in fixtures: %w", what, code, jquants.ErrNotFound)
+	}
+	if !fx.covers(from, to) {
+		return nil, fmt.Errorf("%s for %s - %s not in fixtures: %w", what, from, to, jquants.ErrNotFound)
+	}
+	return items, nil
+}
+
+// from〜to (空なら制限なし) がサンプルデータの取引カレンダーの範囲と重なるか
+func (fx *Fixtures) covers(from, to string) bool {
+	from, to = normalizeDate(from), normalizeDate(to)
+	for _, d := range fx.TradingCalendar {
+		if (from == "" || d.Date >= from) && (to == "" || d.Date <= to) {
+			return true
+		}
+	}
+	return false
+}
+
+func filter[T any](items []T, keep func(T) bool) []T {
+	var out []T
+	for _, item := range items {
+		if keep(item) {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,68 @@
+package jquantstest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+)
+
+func TestFixturesNotFound(t *testing.T) {
+	fx := DefaultFixtures()
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		get      func() (int, error)
+		notFound bool
+	}{
+		{"known code in range", func() (int, error) {
+			q, err := fx.GetDailyQuotesWithMode(ctx, "72030", "2025-07-01", "2025-07-31", jquants.PriceAdjusted)
+			return len(q), err
+		}, false},
+		{"4-digit code", func() (int, error) {
+			q, err := fx.GetDailyQuotesWithMode(ctx, "7203", "2025-07-01", "2025-07-31", jquants.PriceAdjusted)
+			return len(q), err
+		}, false},
+		// 範囲内の休場日は空の結果でよい
+		{"weekend in range", func() (int, error) {
+			q, err := fx.GetDailyQuotesWithMode(ctx, "72030", "2025-07-19", "2025-07-20", jquants.PriceAdjusted)
+			return len(q), err
+		}, false},
+		{"unknown code", func() (int, error) {
+			q, err := fx.GetDailyQuotesWithMode(ctx, "99999", "2025-07-01", "2025-07-31", jquants.PriceAdjusted)
+			return len(q), err
+		}, true},
+		{"dates before fixtures", func() (int, error) {
+			q, err := fx.GetDailyQuotesWithMode(ctx, "72030", "2025-01-01", "2025-01-31", jquants.PriceAdjusted)
+			return len(q), err
+		}, true},
+		{"statements on a date outside fixtures", func() (int, error) {
+			s, err := fx.GetStatementsContext(ctx, "2026-01-05")
+			return len(s), err
+		}, true},
+		{"TOPIX after fixtures", func() (int, error) {
+			q, err := fx.GetTopix(ctx, "2025-09-01", "2025-09-30")
+			return len(q), err
+		}, true},
+		{"unknown index", func() (int, error) {
+			q, err := fx.GetIndexQuotes(ctx, "9999", "2025-07-01", "2025-07-31")
+			return len(q), err
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := tt.get()
+			if tt.notFound {
+				if !errors.Is(err, jquants.ErrNotFound) {
+					t.Errorf("got %d items, err %v; want ErrNotFound", n, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("err = %v, want nil", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,323 @@
+package jquantstest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+)
+
+// 偽サーバーが受け付けるリフレッシュトークン
+const RefreshToken = "test-refresh-token"
+
+// 1ページあたりの件数のデフォルト (ページングを確実に通るよう小さめにしている)
+const DefaultPageSize = 50
+
+// エンドポイントに注入するエラー
+type InjectedError struct {
+	Status     int
+	Body       string
+	RetryAfter string // 空でなければ Retry-After ヘッダーとして返す
+	Times      int    // 何回返すか (0 以下なら無制限)
+}
+
+// J-Quants API の偽実装 (http.Handler)
+// cmd から http.ListenAndServe で立ち上げることも、NewServer で httptest として使うこともできる
+type Handler struct {
+	Fixtures *Fixtures
+	PageSize int
+
+	mu       sync.Mutex
+	tokens   map[string]bool // 発行済みで有効な IDToken
+	issued   int
+	errors   map[string][]*InjectedError
+	requests map[string]int
+}
+
+func NewHandler(fx *Fixtures) *Handler {
+	if fx == nil {
+		fx = DefaultFixtures()
+	}
+	return &Handler{
+		Fixtures: fx,
+		PageSize: DefaultPageSize,
+		tokens:   make(map[string]bool),
+		errors:   make(map[string][]*InjectedError),
+		requests: make(map[string]int),
+	}
+}
+
+// path (例: "/prices/daily_quotes") へのリクエストに e を返すようにする
+// 複数注入した場合は注入順に消費される
+func (h *Handler) InjectError(path string, e InjectedError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.errors[path] = append(h.errors[path], &e)
+}
+
+// 発行済みの IDToken をすべて失効させる (期限切れ・401 の再現用)
+func (h *Handler) ExpireTokens() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	clear(h.tokens)
+}
+
+// path へのリクエスト回数 (注入エラーや401になったものも含む)
+func (h *Handler) Requests(path string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.requests[path]
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/v1")
+
+	h.mu.Lock()
+	h.requests[path]++
+	injected := h.popError(path)
+	h.mu.Unlock()
+
+	if injected != nil {
+		if injected.RetryAfter != "" {
+			w.Header().Set("Retry-After", injected.RetryAfter)
+		}
+		writeJSON(w, injected.Status, map[string]string{"message": injected.Body})
+		return
+	}
+
+	if path == jquants.AuthEndpoint {
+		h.serveAuth(w, r)
+		return
+	}
+	if !h.authorized(r) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "The incoming token is invalid or expired."})
+		return
+	}
+
+	q := r.URL.Query()
+	fx := h.Fixtures
+	switch path {
+	case jquants.FinsEndpoint:
+		serveList(h, w, r, "statements", fx.Statements, func(s jquants.FinancialStatement) bool {
+			return matchCode(q.Get("code"), s.LocalCode) && matchDate(q.Get("date"), s.DisclosedDate)
+		})
+	case jquants.ListedInfoEndpoint:
+		serveList(h, w, r, "info", listedInfoAsOf(fx.ListedInfo, normalizeDate(q.Get("date"))), func(i jquants.ListedInfo) bool {
+			return matchCode(q.Get("code"), i.Code)
+		})
+	case jquants.DailyQuotesEndpoint:
+		if q.Get("code") == "" && q.Get("date") == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": "This API requires at least 1 parameter as follows; 'date','code'."})
+			return
+		}
+		serveList(h, w, r, "daily_quotes", fx.DailyQuotes, func(d jquants.DailyQuote) bool {
+			return matchCode(q.Get("code"), d.Code) && matchDate(q.Get("date"), d.Date) && inRange(q, d.Date)
+		})
+	case jquants.TradingCalendarEndpoint:
+		serveList(h, w, r, "trading_calendar", fx.TradingCalendar, func(d jquants.TradingCalendarDay) bool {
+			return inRange(q, d.Date)
+		})
+	case jquants.AnnouncementEndpoint:
+		serveList(h, w, r, "announcement", fx.Announcements, func(jquants.Announcement) bool { return true })
+	case jquants.TopixEndpoint:
+		serveList(h, w, r, "topix", fx.Topix, func(d jquants.IndexQuote) bool {
+			return inRange(q, d.Date)
+		})
+	case jquants.IndicesEndpoint:
+		serveList(h, w, r, "indices", fx.Indices, func(d jquants.IndexQuote) bool {
+			return matchCode(q.Get("code"), d.Code) && matchDate(q.Get("date"), d.Date) && inRange(q, d.Date)
+		})
+	case jquants.WeeklyMarginInterestEndpoint:
+		serveList(h, w, r, "weekly_margin_interest", fx.MarginInterest, func(m jquants.WeeklyMarginInterest) bool {
+			return matchCode(q.Get("code"), m.Code) && matchDate(q.Get("date"), m.Date) && inRange(q, m.Date)
+		})
+	case jquants.ShortSellingEndpoint:
+		serveList(h, w, r, "short_selling", fx.ShortSelling, func(s jquants.ShortSelling) bool {
+			return (q.Get("sector33code") == "" || q.Get("sector33code") == s.Sector33Code) &&
+				matchDate(q.Get("date"), s.Date) && inRange(q, s.Date)
+		})
+	case jquants.DividendEndpoint:
+		serveList(h, w, r, "dividend", fx.Dividends, func(d jquants.Dividend) bool {
+			return matchCode(q.Get("code"), d.Code) && matchDate(q.Get("date"), d.AnnouncementDate) && inRange(q, d.AnnouncementDate)
+		})
+	case jquants.FSDetailsEndpoint:
+		serveList(h, w, r, "fs_details", fx.FSDetails, func(d jquants.FSDetails) bool {
+			return matchCode(q.Get("code"), d.LocalCode) && matchDate(q.Get("date"), d.DisclosedDate)
+		})
+	case jquants.TradesSpecEndpoint:
+		serveList(h, w, r, "trades_spec", fx.TradesSpec, func(t jquants.TradesSpec) bool {
+			return (q.Get("section") == "" || q.Get("section") == t.Section) && inRange(q, t.PublishedDate)
+		})
+	case jquants.PricesAMEndpoint:
+		serveList(h, w, r, "prices_am", fx.PricesAM, func(m jquants.MorningQuote) bool {
+			return matchCode(q.Get("code"), m.Code)
+		})
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "not found"})
+	}
+}
+
+// h.mu を保持した状態で呼ぶこと
+func (h *Handler) popError(path string) *InjectedError {
+	queue := h.errors[path]
+	if len(queue) == 0 {
+		return nil
+	}
+	e := queue[0]
+	if e.Times > 0 {
+		e.Times--
+		if e.Times == 0 {
+			h.errors[path] = queue[1:]
+		}
+	}
+	return e
+}
+
+func (h *Handler) serveAuth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"message": "method not allowed"})
+		return
+	}
+	if r.URL.Query().Get("refreshtoken") != RefreshToken {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "'refreshtoken' is incorrect."})
+		return
+	}
+
+	h.mu.Lock()
+	h.issued++
+	token := "test-id-token-" + strconv.Itoa(h.issued)
+	h.tokens[token] = true
+	h.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]string{"idToken": token})
+}
+
+func (h *Handler) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.tokens[token]
+}
+
+// items を filter で絞り込み、PageSize 件ずつ pagination_key 付きで返す
+// pagination_key には次ページの先頭位置を入れている
+func serveList[T any](h *Handler, w http.ResponseWriter, r *http.Request, key string, items []T, keep func(T) bool) {
+	matched := filter(items, keep)
+
+	offset := 0
+	if pk := r.URL.Query().Get("pagination_key"); pk != "" {
+		n, err := strconv.Atoi(pk)
+		if err != nil || n < 0 || n > len(matched) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": "invalid pagination_key"})
+			return
+		}
+		offset = n
+	}
+
+	end := len(matched)
+	if h.PageSize > 0 {
+		end = min(offset+h.PageSize, len(matched))
+	}
+
+	resp := map[string]any{key: append([]T{}, matched[offset:end]...)}
+	if end < len(matched) {
+		resp["pagination_key"] = strconv.Itoa(end)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// 銘柄ごとに date 時点で有効な (適用日が date 以前で最新の) 上場情報だけを残す
+// date が空なら最新の情報
+func listedInfoAsOf(items []jquants.ListedInfo, date string) []jquants.ListedInfo {
+	latest := make(map[string]int)
+	var codes []string
+	for i, info := range items {
+		if date != "" && info.Date > date {
+			continue
+		}
+		j, ok := latest[info.Code]
+		if !ok {
+			codes = append(codes, info.Code)
+		}
+		if !ok || info.Date >= items[j].Date {
+			latest[info.Code] = i
+		}
+	}
+
+	out := make([]jquants.ListedInfo, 0, len(codes))
+	for _, code := range codes {
+		out = append(out, items[latest[code]])
+	}
+	return out
+}
+
+func matchCode(want, code string) bool {
+	// J-Quants は4桁コードでも5桁コードでも検索できる
+	return want == "" || code == want || (len(want) == 4 && strings.HasPrefix(code, want))
+}
+
+func matchDate(want, date string) bool {
+	return want == "" || normalizeDate(want) == date
+}
+
+func inRange(q map[string][]string, date string) bool {
+	get := func(k string) string {
+		if v := q[k]; len(v) > 0 {
+			return normalizeDate(v[0])
+		}
+		return ""
+	}
+	return between(date, get("from"), get("to"))
+}
+
+// from / to は空なら制限なし
+func between(date, from, to string) bool {
+	from, to = normalizeDate(from), normalizeDate(to)
+	return (from == "" || date >= from) && (to == "" || date <= to)
+}
+
+// YYYYMMDD を YYYY-MM-DD に揃える
+func normalizeDate(s string) string {
+	if t, err := time.Parse("20060102", s); err == nil {
+		return t.Format("2006-01-02")
+	}
+	return s
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// httptest で起動した偽サーバー
+type Server struct {
+	*httptest.Server
+	*Handler
+}
+
+// fx が nil なら同梱のサンプルデータを使う。使い終わったら Close すること
+func NewServer(fx *Fixtures) *Server {
+	h := NewHandler(fx)
+	return &Server{Server: httptest.NewServer(h), Handler: h}
+}
+
+// 偽サーバーに接続する jquants.Client を返す
+// テストが遅くならないよう、レート制限なし・再試行の待ち時間を短くしてある (opts で上書き可)
+func (s *Server) JQuantsClient(opts ...jquants.Option) *jquants.Client {
+	base := []jquants.Option{
+		jquants.WithBaseURL(s.URL),
+		jquants.WithHTTPClient(s.Server.Client()),
+		jquants.WithRateLimit(0, 0),
+		jquants.WithRetryPolicy(jquants.RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}),
+	}
+	return jquants.NewClient(RefreshToken, append(base, opts...)...)
+}
@@ -0,0 +1,117 @@
+package jquantstest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquantstest"
+)
+
+func TestDailyQuotesPagination(t *testing.T) {
+	srv := jquantstest.NewServer(nil)
+	defer srv.Close()
+	srv.PageSize = 5
+
+	quotes, err := srv.JQuantsClient().GetDailyQuotesContext(context.Background(), "72030", "2025-06-02", "2025-07-31")
+	if err != nil {
+		t.Fatalf("GetDailyQuotes: %v", err)
+	}
+	var want int
+	for _, q := range srv.Fixtures.DailyQuotes {
+		if q.Code == "72030" {
+			want++
+		}
+	}
+	if len(quotes) != want {
+		t.Errorf("got %d quotes, want %d", len(quotes), want)
+	}
+	for i := 1; i < len(quotes); i++ {
+		if quotes[i].Date <= quotes[i-1].Date {
+			t.Fatalf("quotes not in date order at %d: %s after %s", i, quotes[i].Date, quotes[i-1].Date)
+		}
+	}
+	// 43件を5件ずつ → 9ページ
+	if n, pages := srv.Requests(jquants.DailyQuotesEndpoint), (want+4)/5; n != pages {
+		t.Errorf("daily_quotes requests = %d, want %d pages", n, pages)
+	}
+}
+
+func TestInjectedErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         jquantstest.InjectedError
+		wantErr     error // nil なら再試行で成功する
+		wantRetries int
+	}{
+		{"429 recovers", jquantstest.InjectedError{Status: http.StatusTooManyRequests, RetryAfter: "1", Times: 2}, nil, 2},
+		{"503 recovers", jquantstest.InjectedError{Status: http.StatusServiceUnavailable, RetryAfter: "1", Times: 1}, nil, 1},
+		{"429 persists", jquantstest.InjectedError{Status: http.StatusTooManyRequests, RetryAfter: "1"}, jquants.ErrRateLimited, 2},
+		{"500 persists", jquantstest.InjectedError{Status: http.StatusInternalServerError, RetryAfter: "1"}, nil, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := jquantstest.NewServer(nil)
+			defer srv.Close()
+			srv.InjectError(jquants.DailyQuotesEndpoint, tt.err)
+
+			quotes, err := srv.JQuantsClient().GetDailyQuotesContext(context.Background(), "72030", "2025-07-01", "2025-07-31")
+			if n := srv.Requests(jquants.DailyQuotesEndpoint); n != tt.wantRetries+1 {
+				t.Errorf("requests = %d, want %d", n, tt.wantRetries+1)
+			}
+			if tt.err.Times > 0 {
+				if err != nil || len(quotes) == 0 {
+					t.Fatalf("got %d quotes, err %v; want success after retries", len(quotes), err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("want error after retries are exhausted")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			var apiErr *jquants.APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.err.Status || apiErr.Retries != tt.wantRetries {
+				t.Errorf("err = %#v, want APIError with status %d after %d retries", err, tt.err.Status, tt.wantRetries)
+			}
+			if !strings.Contains(err.Error(), "after 2 retries") {
+				t.Errorf("err = %v, want retry count in message", err)
+			}
+		})
+	}
+}
+
+func TestExpiredTokenRefreshesOnce(t *testing.T) {
+	srv := jquantstest.NewServer(nil)
+	defer srv.Close()
+	client := srv.JQuantsClient()
+	ctx := context.Background()
+
+	if _, err := client.GetListedInfo(ctx, "72030", ""); err != nil {
+		t.Fatalf("GetListedInfo: %v", err)
+	}
+	if n := srv.Requests(jquants.AuthEndpoint); n != 1 {
+		t.Fatalf("auth_refresh requests = %d, want 1", n)
+	}
+
+	srv.ExpireTokens()
+	quotes, err := client.GetDailyQuotesContext(ctx, "72030", "2025-07-01", "2025-07-31")
+	if err != nil {
+		t.Fatalf("GetDailyQuotes after token expiry: %v", err)
+	}
+	if len(quotes) == 0 {
+		t.Error("no quotes after token refresh")
+	}
+	if n := srv.Requests(jquants.AuthEndpoint); n != 2 {
+		t.Errorf("auth_refresh requests = %d, want 2 (one refresh after expiry)", n)
+	}
+	// 401 になった1回と、更新したトークンでの再試行1回
+	if n := srv.Requests(jquants.DailyQuotesEndpoint); n != 2 {
+		t.Errorf("daily_quotes requests = %d, want 2", n)
+	}
+}
@@ -0,0 +1,100 @@
+// Package marketdata は分析・バックテストが使う市場データの取得元を抽象化する。
+// J-Quants API (jquants.Client)、cmd/sync のローカルデータストア (store.Store)、
+// 同梱のサンプルデータ (jquantstest.Fixtures) のどれでも同じように使える。
+package marketdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+)
+
+// すべての取得元が提供する基本のデータ (決算・株価・上場銘柄情報・取引カレンダー)
+// 期間のデータを持っていない場合は空の結果ではなくエラーを返すこと
+type MarketData interface {
+	GetStatementsContext(ctx context.Context, targetDate string) ([]jquants.FinancialStatement, error)
+	GetDailyQuotesWithMode(ctx context.Context, code string, fromDate string, toDate string, mode jquants.PriceMode) ([]jquants.DailyQuote, error)
+	GetListedInfo(ctx context.Context, code string, date string) ([]jquants.ListedInfo, error)
+	GetCompanyInfo(ctx context.Context, code string) (*jquants.ListedInfo, error)
+	GetTradingCalendar(ctx context.Context, fromDate string, toDate string) ([]jquants.TradingCalendarDay, error)
+}
+
+// 以下は取得元によっては提供されないデータ。Extension で取り出して使う
+
+// 銘柄ごとの過去の決算 (前年同期・前回予想との比較用)
+type StatementHistoryData interface {
+	GetStatementsByCode(ctx context.Context, code string) ([]jquants.FinancialStatement, error)
+}
+
+// TOPIX・業種別指数
+type IndexData interface {
+	GetTopix(ctx context.Context, fromDate string, toDate string) ([]jquants.IndexQuote, error)
+	GetIndexQuotes(ctx context.Context, code string, fromDate string, toDate string) ([]jquants.IndexQuote, error)
+}
+
+// 信用残・業種別空売り比率
+type MarginData interface {
+	GetWeeklyMarginInterest(ctx context.Context, code string, fromDate string, toDate string) ([]jquants.WeeklyMarginInterest, error)
+	GetShortSelling(ctx context.Context, sector33Code string, fromDate string, toDate string) ([]jquants.ShortSelling, error)
+}
+
+// 配当・財務諸表の詳細
+type DividendData interface {
+	GetDividends(ctx context.Context, code string, fromDate string, toDate string) ([]jquants.Dividend, error)
+	GetFSDetails(ctx context.Context, code string) ([]jquants.FSDetails, error)
+}
+
+// 投資部門別売買状況
+type TradesSpecData interface {
+	GetTradesSpec(ctx context.Context, section string, fromDate string, toDate string) ([]jquants.TradesSpec, error)
+}
+
+// 当日の前場四本値
+type MorningSessionData interface {
+	GetPricesAM(ctx context.Context, code string) ([]jquants.MorningQuote, error)
+}
+
+// 決算発表予定
+type AnnouncementData interface {
+	GetAnnouncements(ctx context.Context) ([]jquants.Announcement, error)
+}
+
+// 取得元がそのデータを提供していない場合のエラー
+var ErrUnsupported = errors.New("not supported by this data source")
+
+// 別の取得元を包んで動作を変えるもの (ルックアヘッド防止など)
+// 包む側は拡張インターフェースをすべて実装しておき、実際に使えるかは Unwrap した中身で決まる
+type Wrapper interface {
+	MarketData
+	Unwrap() MarketData
+}
+
+// md が拡張インターフェース T も実装していれば T として返す。実装していなければ ErrUnsupported
+func Extension[T any](md MarketData) (T, error) {
+	ext, ok := md.(T)
+	if !ok {
+		return ext, fmt.Errorf("%w: %T does not provide %s", ErrUnsupported, md, reflect.TypeFor[T]().Name())
+	}
+	if w, isWrapper := md.(Wrapper); isWrapper {
+		if _, err := Extension[T](w.Unwrap()); err != nil {
+			var zero T
+			return zero, err
+		}
+	}
+	return ext, nil
+}
+
+// J-Quants API はすべてのデータを提供する
+var (
+	_ MarketData           = (*jquants.Client)(nil)
+	_ StatementHistoryData = (*jquants.Client)(nil)
+	_ IndexData            = (*jquants.Client)(nil)
+	_ MarginData           = (*jquants.Client)(nil)
+	_ DividendData         = (*jquants.Client)(nil)
+	_ TradesSpecData       = (*jquants.Client)(nil)
+	_ MorningSessionData   = (*jquants.Client)(nil)
+	_ AnnouncementData     = (*jquants.Client)(nil)
+)
@@ -0,0 +1,355 @@
+// Package pointintime は過去日の分析で「その時点ではまだ知り得なかったデータ」を返さないようにする (ルックアヘッドバイアス対策)。
+// 分析時点 ("今") は ctx で渡し (WithNow)、Guard で包んだ取得元はそれより後に確定・公表された株価や決算を返さない。
+package pointintime
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/calendar"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/marketdata"
+)
+
+const dateLayout = "2006-01-02"
+
+type nowKey struct{}
+
+// ctx に分析時点を持たせる
+func WithNow(ctx context.Context, now time.Time) context.Context {
+	return context.WithValue(ctx, nowKey{}, now)
+}
+
+// ctx の分析時点。持っていなければ false (Guard は何も制限しない)
+func Now(ctx context.Context) (time.Time, bool) {
+	now, ok := ctx.Value(nowKey{}).(time.Time)
+	return now, ok
+}
+
+// J-Quants の日付と時刻 ("15:04:05" / "15:04") を JST の時刻にする
+// 時刻が空ならその日の終わり (翌日 0:00) とみなす (いつ公表されたか分からないものは当日中は見えない側に倒す)
+func At(date string, clock string) (time.Time, error) {
+	date = normalizeDate(date)
+	if clock == "" {
+		t, err := time.ParseInLocation(dateLayout, date, calendar.JST)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return t.AddDate(0, 0, 1), nil
+	}
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if t, err := time.ParseInLocation(dateLayout+" "+layout, date+" "+clock, calendar.JST); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date/time %q %q", date, clock)
+}
+
+// 信用残は週末 (Date) 時点の残高が翌週第2営業日の夕方に公表されるので、4日後の大引け後から見えるものとする
+// (祝日で公表が遅れる週は考慮していない)
+const marginPublicationDays = 4
+
+// 取得元を包んで、ctx の分析時点より後のデータを取り除く
+// 分析時点より後の日付を要求された場合は分析時点までに切り詰め、違反としてエラーログを出す
+// 株価は大引け (15:30) 後に確定するものとし、前場引け後〜大引け前は前場の四本値だけの足を返す
+// 調整済み価格もその後の分割を知らない状態 (分析時点基準) に計算し直す
+type Guard struct {
+	Data marketdata.MarketData
+}
+
+var (
+	_ marketdata.Wrapper              = (*Guard)(nil)
+	_ marketdata.StatementHistoryData = (*Guard)(nil)
+	_ marketdata.IndexData            = (*Guard)(nil)
+	_ marketdata.MarginData           = (*Guard)(nil)
+	_ marketdata.DividendData         = (*Guard)(nil)
+	_ marketdata.TradesSpecData       = (*Guard)(nil)
+	_ marketdata.MorningSessionData   = (*Guard)(nil)
+	_ marketdata.AnnouncementData     = (*Guard)(nil)
+)
+
+func (g *Guard) Unwrap() marketdata.MarketData {
+	return g.Data
+}
+
+func (g *Guard) GetStatementsContext(ctx context.Context, targetDate string) ([]jquants.FinancialStatement, error) {
+	now, ok := Now(ctx)
+	if !ok {
+		return g.Data.GetStatementsContext(ctx, targetDate)
+	}
+	if normalizeDate(targetDate) > now.In(calendar.JST).Format(dateLayout) {
+		violation(now, "statements disclosed on %s", targetDate)
+		return nil, nil
+	}
+	statements, err := g.Data.GetStatementsContext(ctx, targetDate)
+	if err != nil {
+		return nil, err
+	}
+	return visible(statements, func(s jquants.FinancialStatement) bool {
+		return known(now, s.DisclosedDate, s.DisclosedTime)
+	}), nil
+}
+
+func (g *Guard) GetStatementsByCode(ctx context.Context, code string) ([]jquants.FinancialStatement, error) {
+	ext, err := marketdata.Extension[marketdata.StatementHistoryData](g.Data)
+	if err != nil {
+		return nil, err
+	}
+	statements, err := ext.GetStatementsByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	now, ok := Now(ctx)
+	if !ok {
+		return statements, nil
+	}
+	return visible(statements, func(s jquants.FinancialStatement) bool {
+		return known(now, s.DisclosedDate, s.DisclosedTime)
+	}), nil
+}
+
+func (g *Guard) GetDailyQuotesWithMode(ctx context.Context, code string, fromDate string, toDate string, mode jquants.PriceMode) ([]jquants.DailyQuote, error) {
+	now, ok := Now(ctx)
+	if !ok {
+		return g.Data.GetDailyQuotesWithMode(ctx, code, fromDate, toDate, mode)
+	}
+	toDate = clampTo(now, toDate, "daily quotes of "+code)
+	if normalizeDate(fromDate) > toDate {
+		return nil, nil
+	}
+
+	// 調整済み価格は計算し直すので生値で取る
+	quotes, err := g.Data.GetDailyQuotesWithMode(ctx, code, fromDate, toDate, jquants.PriceRaw)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]jquants.DailyQuote, 0, len(quotes))
+	for _, q := range quotes {
+		switch {
+		case known(now, q.Date, calendar.AfternoonClose):
+			out = append(out, q)
+		case known(now, q.Date, calendar.MorningClose) && q.MorningOpen > 0:
+			out = append(out, morningOnly(q))
+		}
+	}
+	jquants.Readjust(out, 1)
+	return jquants.ApplyPriceMode(out, mode), nil
+}
+
+// 前場の四本値だけの足 (大引け前の当日分)
+func morningOnly(q jquants.DailyQuote) jquants.DailyQuote {
+	q.Open, q.High, q.Low, q.Close, q.Volume, q.TurnoverValue = q.MorningOpen, q.MorningHigh, q.MorningLow, q.MorningClose, q.MorningVolume, q.MorningTurnoverValue
+	q.AfternoonOpen, q.AfternoonHigh, q.AfternoonLow, q.AfternoonClose, q.AfternoonVolume, q.AfternoonTurnoverValue = 0, 0, 0, 0, 0, 0
+	return q
+}
+
+func (g *Guard) GetListedInfo(ctx context.Context, code string, date string) ([]jquants.ListedInfo, error) {
+	now, ok := Now(ctx)
+	if !ok {
+		return g.Data.GetListedInfo(ctx, code, date)
+	}
+	date = clampTo(now, date, "listed info")
+	return g.Data.GetListedInfo(ctx, code, date)
+}
+
+func (g *Guard) GetCompanyInfo(ctx context.Context, code string) (*jquants.ListedInfo, error) {
+	if _, ok := Now(ctx); !ok {
+		return g.Data.GetCompanyInfo(ctx, code)
+	}
+	// 最新ではなく分析時点の上場情報を返す
+	infos, err := g.GetListedInfo(ctx, code, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(infos) == 0 {
+		return nil, fmt.Errorf("listed info for %s: %w", code, jquants.ErrNotFound)
+	}
+	return &infos[0], nil
+}
+
+// 取引カレンダーは先の予定まで公表されているので制限しない
+func (g *Guard) GetTradingCalendar(ctx context.Context, fromDate string, toDate string) ([]jquants.TradingCalendarDay, error) {
+	return g.Data.GetTradingCalendar(ctx, fromDate, toDate)
+}
+
+func (g *Guard) GetTopix(ctx context.Context, fromDate string, toDate string) ([]jquants.IndexQuote, error) {
+	ext, err := marketdata.Extension[marketdata.IndexData](g.Data)
+	if err != nil {
+		return nil, err
+	}
+	return guardRange(ctx, fromDate, toDate, "TOPIX", func(to string) ([]jquants.IndexQuote, error) {
+		return ext.GetTopix(ctx, fromDate, to)
+	}, func(now time.Time, d jquants.IndexQuote) bool {
+		return known(now, d.Date, calendar.AfternoonClose)
+	})
+}
+
+func (g *Guard) GetIndexQuotes(ctx context.Context, code string, fromDate string, toDate string) ([]jquants.IndexQuote, error) {
+	ext, err := marketdata.Extension[marketdata.IndexData](g.Data)
+	if err != nil {
+		return nil, err
+	}
+	return guardRange(ctx, fromDate, toDate, "index "+code, func(to string) ([]jquants.IndexQuote, error) {
+		return ext.GetIndexQuotes(ctx, code, fromDate, to)
+	}, func(now time.Time, d jquants.IndexQuote) bool {
+		return known(now, d.Date, calendar.AfternoonClose)
+	})
+}
+
+func (g *Guard) GetWeeklyMarginInterest(ctx context.Context, code string, fromDate string, toDate string) ([]jquants.WeeklyMarginInterest, error) {
+	ext, err := marketdata.Extension[marketdata.MarginData](g.Data)
+	if err != nil {
+		return nil, err
+	}
+	return guardRange(ctx, fromDate, toDate, "margin interest of "+code, func(to string) ([]jquants.WeeklyMarginInterest, error) {
+		return ext.GetWeeklyMarginInterest(ctx, code, fromDate, to)
+	}, func(now time.Time, m jquants.WeeklyMarginInterest) bool {
+		d, err := time.Parse(dateLayout, m.Date)
+		return err == nil && known(now, d.AddDate(0, 0, marginPublicationDays).Format(dateLayout), calendar.AfternoonClose)
+	})
+}
+
+func (g *Guard) GetShortSelling(ctx context.Context, sector33Code string, fromDate string, toDate string) ([]jquants.ShortSelling, error) {
+	ext, err := marketdata.Extension[marketdata.MarginData](g.Data)
+	if err != nil {
+		return nil, err
+	}
+	return guardRange(ctx, fromDate, toDate, "short selling of sector "+sector33Code, func(to string) ([]jquants.ShortSelling, error) {
+		return ext.GetShortSelling(ctx, sector33Code, fromDate, to)
+	}, func(now time.Time, s jquants.ShortSelling) bool {
+		return known(now, s.Date, calendar.AfternoonClose)
+	})
+}
+
+func (g *Guard) GetDividends(ctx context.Context, code string, fromDate string, toDate string) ([]jquants.Dividend, error) {
+	ext, err := marketdata.Extension[marketdata.DividendData](g.Data)
+	if err != nil {
+		return nil, err
+	}
+	return guardRange(ctx, fromDate, toDate, "dividends of "+code, func(to string) ([]jquants.Dividend, error) {
+		return ext.GetDividends(ctx, code, fromDate, to)
+	}, func(now time.Time, d jquants.Dividend) bool {
+		return known(now, d.AnnouncementDate, d.AnnouncementTime)
+	})
+}
+
+func (g *Guard) GetFSDetails(ctx context.Context, code string) ([]jquants.FSDetails, error) {
+	ext, err := marketdata.Extension[marketdata.DividendData](g.Data)
+	if err != nil {
+		return nil, err
+	}
+	details, err := ext.GetFSDetails(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	now, ok := Now(ctx)
+	if !ok {
+		return details, nil
+	}
+	return visible(details, func(d jquants.FSDetails) bool {
+		return known(now, d.DisclosedDate, d.DisclosedTime)
+	}), nil
+}
+
+func (g *Guard) GetTradesSpec(ctx context.Context, section string, fromDate string, toDate string) ([]jquants.TradesSpec, error) {
+	ext, err := marketdata.Extension[marketdata.TradesSpecData](g.Data)
+	if err != nil {
+		return nil, err
+	}
+	return guardRange(ctx, fromDate, toDate, "trades spec of "+section, func(to string) ([]jquants.TradesSpec, error) {
+		return ext.GetTradesSpec(ctx, section, fromDate, to)
+	}, func(now time.Time, t jquants.TradesSpec) bool {
+		return known(now, t.PublishedDate, calendar.AfternoonClose)
+	})
+}
+
+func (g *Guard) GetPricesAM(ctx context.Context, code string) ([]jquants.MorningQuote, error) {
+	ext, err := marketdata.Extension[marketdata.MorningSessionData](g.Data)
+	if err != nil {
+		return nil, err
+	}
+	quotes, err := ext.GetPricesAM(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	now, ok := Now(ctx)
+	if !ok {
+		return quotes, nil
+	}
+	return visible(quotes, func(q jquants.MorningQuote) bool {
+		return known(now, q.Date, calendar.MorningClose)
+	}), nil
+}
+
+// 決算発表予定は現在の予定しか取れず、過去のある時点の予定は復元できない
+func (g *Guard) GetAnnouncements(ctx context.Context) ([]jquants.Announcement, error) {
+	ext, err := marketdata.Extension[marketdata.AnnouncementData](g.Data)
+	if err != nil {
+		return nil, err
+	}
+	if now, ok := Now(ctx); ok {
+		return nil, fmt.Errorf("%w: announcement schedule as of %s", marketdata.ErrUnsupported, now.In(calendar.JST).Format(dateLayout))
+	}
+	return ext.GetAnnouncements(ctx)
+}
+
+// 期間指定の取得を分析時点までに切り詰め、まだ公表されていないものを取り除く
+func guardRange[T any](ctx context.Context, fromDate, toDate, what string, fetch func(toDate string) ([]T, error), isKnown func(time.Time, T) bool) ([]T, error) {
+	now, ok := Now(ctx)
+	if !ok {
+		return fetch(toDate)
+	}
+	toDate = clampTo(now, toDate, what)
+	if normalizeDate(fromDate) > toDate {
+		return nil, nil
+	}
+	items, err := fetch(toDate)
+	if err != nil {
+		return nil, err
+	}
+	return visible(items, func(item T) bool { return isKnown(now, item) }), nil
+}
+
+// date (空なら制限なし) を分析時点の日付までに切り詰める。分析時点より後を指していれば違反としてログに残す
+func clampTo(now time.Time, date string, what string) string {
+	today := now.In(calendar.JST).Format(dateLayout)
+	if date == "" {
+		return today
+	}
+	if normalizeDate(date) > today {
+		violation(now, "%s requested up to %s", what, date)
+		return today
+	}
+	return normalizeDate(date)
+}
+
+// date の clock 時点ですでに公表済みか
+func known(now time.Time, date string, clock string) bool {
+	t, err := At(date, clock)
+	return err == nil && !t.After(now)
+}
+
+func visible[T any](items []T, keep func(T) bool) []T {
+	out := make([]T, 0, len(items))
+	for _, item := range items {
+		if keep(item) {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// ツール (モデルが選んだ base_date) などが分析時点より後のデータを要求した
+func violation(now time.Time, format string, args ...any) {
+	log.Printf("ERROR: look-ahead blocked (as of %s JST): %s", now.In(calendar.JST).Format("2006-01-02 15:04"), fmt.Sprintf(format, args...))
+}
+
+// J-Quants と同じく "YYYYMMDD" も受け付ける
+func normalizeDate(s string) string {
+	if t, err := time.Parse("20060102", s); err == nil {
+		return t.Format(dateLayout)
+	}
+	return s
+}
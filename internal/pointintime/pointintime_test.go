@@ -0,0 +1,69 @@
+package pointintime
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/calendar"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquantstest"
+)
+
+func TestKnown(t *testing.T) {
+	jst := func(day, hour, min int) time.Time {
+		return time.Date(2025, 7, day, hour, min, 0, 0, calendar.JST)
+	}
+	tests := []struct {
+		name  string
+		date  string
+		clock string
+		now   time.Time
+		want  bool
+	}{
+		{"disclosed before now", "2025-07-18", "15:00:00", jst(18, 15, 30), true},
+		{"disclosed at now", "2025-07-18", "15:00", jst(18, 15, 0), true},
+		{"disclosed after now", "2025-07-18", "15:00:00", jst(18, 14, 59), false},
+		{"YYYYMMDD date", "20250718", "15:00:00", jst(18, 15, 30), true},
+		// 時刻が分からないものは当日中は見えない
+		{"unknown time, same day morning", "2025-07-18", "", jst(18, 9, 0), false},
+		{"unknown time, same day late", "2025-07-18", "", jst(18, 23, 59), false},
+		{"unknown time, next day", "2025-07-18", "", jst(19, 0, 0), true},
+		{"invalid time", "2025-07-18", "afternoon", jst(19, 0, 0), false},
+	}
+	for _, tt := range tests {
+		if got := known(tt.now, tt.date, tt.clock); got != tt.want {
+			t.Errorf("%s: known(%s, %q, %q) = %v, want %v", tt.name, tt.now.Format(time.DateTime), tt.date, tt.clock, got, tt.want)
+		}
+	}
+}
+
+func TestGuardHidesStatementsWithUnknownTime(t *testing.T) {
+	fx := &jquantstest.Fixtures{
+		Statements: []jquants.FinancialStatement{
+			{DisclosedDate: "2025-07-18", DisclosedTime: "", LocalCode: "72030"},
+			{DisclosedDate: "2025-07-18", DisclosedTime: "08:30:00", LocalCode: "67580"},
+		},
+		ListedInfo:      []jquants.ListedInfo{{Code: "72030"}, {Code: "67580"}},
+		TradingCalendar: []jquants.TradingCalendarDay{{Date: "2025-07-18", HolidayDivision: "1"}},
+	}
+	g := &Guard{Data: fx}
+
+	ctx := WithNow(context.Background(), time.Date(2025, 7, 18, 9, 0, 0, 0, calendar.JST))
+	got, err := g.GetStatementsContext(ctx, "2025-07-18")
+	if err != nil {
+		t.Fatalf("GetStatementsContext: %v", err)
+	}
+	if len(got) != 1 || got[0].LocalCode != "67580" {
+		t.Errorf("at 09:00 got %+v, want only the statement disclosed at 08:30", got)
+	}
+
+	ctx = WithNow(context.Background(), time.Date(2025, 7, 19, 0, 0, 0, 0, calendar.JST))
+	got, err = g.GetStatementsContext(ctx, "2025-07-18")
+	if err != nil {
+		t.Fatalf("GetStatementsContext: %v", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("on the next day got %d statements, want 2", len(got))
+	}
+}
@@ -0,0 +1,91 @@
+// 海外投資家の売買動向 (投資部門別売買状況) から市場の地合いを判定する
+package regime
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/marketdata"
+)
+
+// 地合いの判定結果
+const (
+	Inflow  = "INFLOW"  // 海外勢が継続的に買い越し
+	Outflow = "OUTFLOW" // 海外勢が継続的に売り越し
+	Mixed   = "MIXED"   // 方向感なし
+)
+
+// 判定に使うデフォルトの週数
+const DefaultWeeks = 4
+
+// 海外投資家フローの集計結果 (金額は千円単位)
+type ForeignFlow struct {
+	Section     string
+	AsOf        string // 集計に使った最新週の公表日
+	Weeks       int
+	LatestWeek  string  // 最新週の期間 ("StartDate - EndDate")
+	LatestNet   float64 // 最新週の差引き (買い - 売り)
+	TotalNet    float64 // Weeks 週分の差引き合計
+	BuyingWeeks int     // 買い越しだった週の数
+	Regime      string
+}
+
+// date 時点で公表済みの直近 weeks 週分から地合いを判定する
+// 公表日で絞るので date より後に公表された週は含まれないが、date 当日 (大引け後) の公表分は含む
+// 当日の取引時間中の時点で判定する場合は md を pointintime.Guard で包むこと
+func ForeignFlowRegime(ctx context.Context, md marketdata.MarketData, section string, date time.Time, weeks int) (*ForeignFlow, error) {
+	trades, err := marketdata.Extension[marketdata.TradesSpecData](md)
+	if err != nil {
+		return nil, err
+	}
+	if weeks <= 0 {
+		weeks = DefaultWeeks
+	}
+	// 祝日で公表がずれる週もあるので少し多めに取る
+	fromDate := date.AddDate(0, 0, -7*(weeks+2)).Format("2006-01-02")
+	records, err := trades.GetTradesSpec(ctx, section, fromDate, date.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch trades spec: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no trades spec published for %s before %s", section, date.Format("2006-01-02"))
+	}
+
+	sort.Slice(records, func(i, j int) bool { return records[i].EndDate < records[j].EndDate })
+	records = records[max(0, len(records)-weeks):]
+	latest := records[len(records)-1]
+
+	flow := &ForeignFlow{
+		Section:    section,
+		AsOf:       latest.PublishedDate,
+		Weeks:      len(records),
+		LatestWeek: latest.StartDate + " - " + latest.EndDate,
+		LatestNet:  latest.ForeignersBalance,
+	}
+	for _, r := range records {
+		flow.TotalNet += r.ForeignersBalance
+		if r.ForeignersBalance > 0 {
+			flow.BuyingWeeks++
+		}
+	}
+
+	// 合計の向きと、過半数の週が同じ向きであることの両方を満たした時だけ方向ありとする
+	switch {
+	case flow.TotalNet > 0 && flow.BuyingWeeks*2 > flow.Weeks:
+		flow.Regime = Inflow
+	case flow.TotalNet < 0 && (flow.Weeks-flow.BuyingWeeks)*2 > flow.Weeks:
+		flow.Regime = Outflow
+	default:
+		flow.Regime = Mixed
+	}
+	return flow, nil
+}
+
+func (f *ForeignFlow) String() string {
+	return fmt.Sprintf(
+		"Foreign Investor Flow (%s, published by %s): %s\nLatest Week (%s): Net %+.1fB JPY\nLast %d Weeks: Net %+.1fB JPY (%d/%d weeks net buying)",
+		f.Section, f.AsOf, f.Regime, f.LatestWeek, f.LatestNet/1e6, f.Weeks, f.TotalNet/1e6, f.BuyingWeeks, f.Weeks,
+	)
+}
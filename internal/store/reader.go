@@ -0,0 +1,341 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/marketdata"
+)
+
+// 以下は jquants.Client と同じシグネチャの読み出しメソッド (marketdata.MarketData の実装)
+// 同期されていない期間を要求された場合は (空の結果ではなく) ErrNotSynced を返す
+
+var (
+	_ marketdata.MarketData           = (*Store)(nil)
+	_ marketdata.StatementHistoryData = (*Store)(nil)
+)
+
+func (s *Store) GetStatements(targetDate string) ([]jquants.FinancialStatement, error) {
+	return s.GetStatementsContext(context.Background(), targetDate)
+}
+
+func (s *Store) GetStatementsContext(ctx context.Context, targetDate string) ([]jquants.FinancialStatement, error) {
+	date := normalizeDate(targetDate)
+	if err := s.checkSynced(ctx, DatasetStatements, date, date, false); err != nil {
+		return nil, err
+	}
+	return queryJSON[jquants.FinancialStatement](ctx, s.db, `SELECT data FROM statements WHERE disclosed_date = ? ORDER BY json_extract(data, '$.DisclosedTime'), disclosure_number`, date)
+}
+
+// 銘柄の過去の決算。同期した期間に開示されたものしかないので、同期開始より前の前年同期などは欠ける
+func (s *Store) GetStatementsByCode(ctx context.Context, code string) ([]jquants.FinancialStatement, error) {
+	var first sql.NullString
+	if err := s.db.QueryRowContext(ctx, `SELECT MIN(date) FROM synced WHERE dataset = ?`, DatasetStatements).Scan(&first); err != nil {
+		return nil, err
+	}
+	if !first.Valid {
+		return nil, fmt.Errorf("%w: %s", ErrNotSynced, DatasetStatements)
+	}
+	return queryJSON[jquants.FinancialStatement](ctx, s.db, `SELECT data FROM statements WHERE local_code = ? ORDER BY disclosed_date, json_extract(data, '$.DisclosedTime'), disclosure_number`, jquants.NormalizeCode(code))
+}
+
+func (s *Store) GetDailyQuotes(code string, fromDate string, toDate string) ([]jquants.DailyQuote, error) {
+	return s.GetDailyQuotesContext(context.Background(), code, fromDate, toDate)
+}
+
+func (s *Store) GetDailyQuotesContext(ctx context.Context, code string, fromDate string, toDate string) ([]jquants.DailyQuote, error) {
+	code, from, to := jquants.NormalizeCode(code), normalizeDate(fromDate), normalizeDate(toDate)
+	if err := s.checkSynced(ctx, DatasetDailyQuotes, from, to, true); err != nil {
+		return nil, err
+	}
+	quotes, err := queryJSON[jquants.DailyQuote](ctx, s.db, `SELECT data FROM daily_quotes WHERE code = ? AND date BETWEEN ? AND ? ORDER BY date`, code, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// 期間より後の分割・併合も反映して、最新日基準の調整済み価格にする
+	later := 1.0
+	rows, err := s.db.QueryContext(ctx, `SELECT adjustment_factor FROM daily_quotes WHERE code = ? AND date > ? AND adjustment_factor NOT IN (0, 1)`, code, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var f float64
+		if err := rows.Scan(&f); err != nil {
+			return nil, err
+		}
+		later *= f
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	jquants.Readjust(quotes, later)
+	return quotes, nil
+}
+
+func (s *Store) GetDailyQuotesWithMode(ctx context.Context, code string, fromDate string, toDate string, mode jquants.PriceMode) ([]jquants.DailyQuote, error) {
+	quotes, err := s.GetDailyQuotesContext(ctx, code, fromDate, toDate)
+	if err != nil {
+		return nil, err
+	}
+	return jquants.ApplyPriceMode(quotes, mode), nil
+}
+
+func (s *Store) GetDailyQuotesByDate(ctx context.Context, date string) ([]jquants.DailyQuote, error) {
+	date = normalizeDate(date)
+	if err := s.checkSynced(ctx, DatasetDailyQuotes, date, date, true); err != nil {
+		return nil, err
+	}
+	quotes, err := queryJSON[jquants.DailyQuote](ctx, s.db, `SELECT data FROM daily_quotes WHERE date = ? ORDER BY code`, date)
+	if err != nil {
+		return nil, err
+	}
+
+	later := make(map[string]float64)
+	rows, err := s.db.QueryContext(ctx, `SELECT code, adjustment_factor FROM daily_quotes WHERE date > ? AND adjustment_factor NOT IN (0, 1)`, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var code string
+		var f float64
+		if err := rows.Scan(&code, &f); err != nil {
+			return nil, err
+		}
+		if _, ok := later[code]; !ok {
+			later[code] = 1
+		}
+		later[code] *= f
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range quotes {
+		cum, ok := later[quotes[i].Code]
+		if !ok {
+			cum = 1
+		}
+		jquants.Readjust(quotes[i:i+1], cum)
+	}
+	return quotes, nil
+}
+
+// date 時点の上場銘柄一覧 (code が空なら全銘柄、date が空なら最新)
+func (s *Store) GetListedInfo(ctx context.Context, code string, date string) ([]jquants.ListedInfo, error) {
+	// date 以前の直近の同期日の一覧を使う (date が空なら最終同期日)
+	// 同期日から date までの間に同期していない立会日があれば古い一覧になってしまうのでエラーにする
+	asOf := normalizeDate(date)
+	if asOf == "" {
+		asOf = "9999-12-31"
+	}
+	var prev sql.NullString
+	err := s.db.QueryRowContext(ctx, `SELECT MAX(date) FROM synced WHERE dataset = ? AND date <= ?`, DatasetListedInfo, asOf).Scan(&prev)
+	if err != nil {
+		return nil, err
+	}
+	if !prev.Valid {
+		return nil, fmt.Errorf("%w: %s as of %s", ErrNotSynced, DatasetListedInfo, asOf)
+	}
+	if date == "" {
+		asOf = prev.String
+	} else if err := s.checkSynced(ctx, DatasetListedInfo, prev.String, asOf, true); err != nil {
+		return nil, err
+	}
+
+	rows, err := listedInfoAsOf(ctx, s.db, asOf, jquants.NormalizeCode(code), false)
+	if err != nil {
+		return nil, err
+	}
+	codes := make([]string, 0, len(rows))
+	for c := range rows {
+		codes = append(codes, c)
+	}
+	sort.Strings(codes)
+
+	infos := make([]jquants.ListedInfo, 0, len(rows))
+	for _, c := range codes {
+		var info jquants.ListedInfo
+		if err := json.Unmarshal([]byte(rows[c].String), &info); err != nil {
+			return nil, err
+		}
+		info.Date = asOf
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func (s *Store) GetListedInfoMap() (map[string]string, error) {
+	return s.GetListedInfoMapContext(context.Background())
+}
+
+func (s *Store) GetListedInfoMapContext(ctx context.Context) (map[string]string, error) {
+	infos, err := s.GetListedInfo(ctx, "", "")
+	if err != nil {
+		return nil, err
+	}
+	nameMap := make(map[string]string, len(infos))
+	for _, info := range infos {
+		nameMap[info.Code] = info.CompanyName
+	}
+	return nameMap, nil
+}
+
+func (s *Store) GetCompanyInfo(ctx context.Context, code string) (*jquants.ListedInfo, error) {
+	infos, err := s.GetListedInfo(ctx, code, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(infos) == 0 {
+		return nil, fmt.Errorf("listed info for %s: %w", code, jquants.ErrNotFound)
+	}
+	return &infos[0], nil
+}
+
+// 取引カレンダー (from / to が空なら全期間)
+func (s *Store) GetTradingCalendar(ctx context.Context, fromDate string, toDate string) ([]jquants.TradingCalendarDay, error) {
+	from, to := normalizeDate(fromDate), normalizeDate(toDate)
+	if from == "" {
+		from = "0000-01-01"
+	}
+	if to == "" {
+		to = "9999-12-31"
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT date, holiday_division FROM trading_calendar WHERE date BETWEEN ? AND ? ORDER BY date`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []jquants.TradingCalendarDay
+	for rows.Next() {
+		var d jquants.TradingCalendarDay
+		if err := rows.Scan(&d.Date, &d.HolidayDivision); err != nil {
+			return nil, err
+		}
+		days = append(days, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(days) == 0 {
+		return nil, fmt.Errorf("%w: trading calendar", ErrNotSynced)
+	}
+	return days, nil
+}
+
+// dataset が from〜to の日付 (tradingDaysOnly なら立会日だけ) をすべて同期済みか確認する
+func (s *Store) checkSynced(ctx context.Context, dataset string, from, to string, tradingDaysOnly bool) error {
+	dates, err := datesBetween(from, to)
+	if err != nil {
+		return fmt.Errorf("invalid date range %q - %q: %w", from, to, err)
+	}
+
+	calendar := make(map[string]string)
+	if tradingDaysOnly {
+		days, err := s.GetTradingCalendar(ctx, from, to)
+		if err != nil {
+			return err
+		}
+		for _, d := range days {
+			calendar[d.Date] = d.HolidayDivision
+		}
+	}
+
+	synced := make(map[string]bool)
+	rows, err := s.db.QueryContext(ctx, `SELECT date FROM synced WHERE dataset = ? AND date BETWEEN ? AND ?`, dataset, from, to)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var d string
+		if err := rows.Scan(&d); err != nil {
+			return err
+		}
+		synced[d] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, d := range dates {
+		if tradingDaysOnly {
+			hd, ok := calendar[d]
+			if !ok {
+				return fmt.Errorf("%w: trading calendar on %s", ErrNotSynced, d)
+			}
+			if !(jquants.TradingCalendarDay{Date: d, HolidayDivision: hd}).IsTradingDay() {
+				continue
+			}
+		}
+		if !synced[d] {
+			return fmt.Errorf("%w: %s on %s", ErrNotSynced, dataset, d)
+		}
+	}
+	return nil
+}
+
+// *sql.DB と *sql.Tx の共通部分
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// date 時点で有効な上場銘柄情報の data 列 (Code -> data)。withDelisted なら上場廃止 (NULL) も含める
+func listedInfoAsOf(ctx context.Context, q queryer, date string, code string, withDelisted bool) (map[string]sql.NullString, error) {
+	query := `
+SELECT l.code, l.data FROM listed_info l
+JOIN (SELECT code, MAX(date) AS date FROM listed_info WHERE date <= ? GROUP BY code) m
+  ON l.code = m.code AND l.date = m.date`
+	args := []any{date}
+	if code != "" {
+		query += ` WHERE l.code = ?`
+		args = append(args, code)
+	}
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[string]sql.NullString)
+	for rows.Next() {
+		var c string
+		var data sql.NullString
+		if err := rows.Scan(&c, &data); err != nil {
+			return nil, err
+		}
+		if data.Valid || withDelisted {
+			out[c] = data
+		}
+	}
+	return out, rows.Err()
+}
+
+// 1列目の JSON を T として読み込む
+func queryJSON[T any](ctx context.Context, q queryer, query string, args ...any) ([]T, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []T
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var v T
+		if err := json.Unmarshal([]byte(data), &v); err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
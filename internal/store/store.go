@@ -0,0 +1,239 @@
+// Package store は cmd/sync がダウンロードした J-Quants のデータを保存するローカルデータストア (SQLite)。
+// 読み出し側は jquants.Client と同じメソッドを持つので、API を呼ばずにオフラインで分析・バックテストできる。
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+
+	_ "modernc.org/sqlite"
+)
+
+// 同期の単位 (データセット名)
+const (
+	DatasetDailyQuotes = "daily_quotes"
+	DatasetStatements  = "statements"
+	DatasetListedInfo  = "listed_info"
+)
+
+// 要求された期間のデータがまだ同期されていない場合のエラー
+var ErrNotSynced = errors.New("data is not synced to the local store")
+
+const dateLayout = "2006-01-02"
+
+// 価格・決算はレスポンスの JSON をそのまま data 列に持つ (構造体にフィールドが増えても作り直さなくて済むように)
+// 調整済み価格は読み出し時に adjustment_factor から計算し直すので、生値と一緒に係数を列として持つ
+const schema = `
+CREATE TABLE IF NOT EXISTS trading_calendar (
+	date             TEXT PRIMARY KEY,
+	holiday_division TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS daily_quotes (
+	code              TEXT NOT NULL,
+	date              TEXT NOT NULL,
+	adjustment_factor REAL NOT NULL,
+	data              TEXT NOT NULL,
+	PRIMARY KEY (code, date)
+);
+CREATE INDEX IF NOT EXISTS daily_quotes_date ON daily_quotes (date);
+CREATE TABLE IF NOT EXISTS statements (
+	disclosure_number TEXT PRIMARY KEY,
+	disclosed_date    TEXT NOT NULL,
+	local_code        TEXT NOT NULL,
+	data              TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS statements_date ON statements (disclosed_date);
+CREATE INDEX IF NOT EXISTS statements_code ON statements (local_code, disclosed_date);
+-- 上場銘柄情報は変化があった日だけ行を追加する。data が NULL の行は上場廃止 (一覧から消えた)
+CREATE TABLE IF NOT EXISTS listed_info (
+	code TEXT NOT NULL,
+	date TEXT NOT NULL,
+	data TEXT,
+	PRIMARY KEY (code, date)
+);
+CREATE TABLE IF NOT EXISTS synced (
+	dataset TEXT NOT NULL,
+	date    TEXT NOT NULL,
+	PRIMARY KEY (dataset, date)
+);
+`
+
+type Store struct {
+	db *sql.DB
+}
+
+// path の SQLite ファイルを開く (なければディレクトリごと作る)
+func Open(path string) (*Store, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	db, err := sql.Open("sqlite", path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init store %s: %w", path, err)
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// dataset の date 分を同期済みか
+func (s *Store) Synced(ctx context.Context, dataset string, date string) (bool, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM synced WHERE dataset = ? AND date = ?`, dataset, date).Scan(&n)
+	return n > 0, err
+}
+
+// dataset の最後に同期した日付 (一度も同期していなければ空文字)
+func (s *Store) LastSynced(ctx context.Context, dataset string) (string, error) {
+	var last sql.NullString
+	err := s.db.QueryRowContext(ctx, `SELECT MAX(date) FROM synced WHERE dataset = ?`, dataset).Scan(&last)
+	return last.String, err
+}
+
+// 取引カレンダーを丸ごと置き換える
+func (s *Store) SaveTradingCalendar(ctx context.Context, days []jquants.TradingCalendarDay) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, d := range days {
+			_, err := tx.ExecContext(ctx, `INSERT OR REPLACE INTO trading_calendar (date, holiday_division) VALUES (?, ?)`, d.Date, d.HolidayDivision)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// date の全銘柄の株価を保存する。markSynced が false なら同期済みにしない (当日分がまだ揃っていない場合など)
+func (s *Store) SaveDailyQuotes(ctx context.Context, date string, quotes []jquants.DailyQuote, markSynced bool) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM daily_quotes WHERE date = ?`, date); err != nil {
+			return err
+		}
+		for _, q := range quotes {
+			data, err := json.Marshal(q)
+			if err != nil {
+				return err
+			}
+			_, err = tx.ExecContext(ctx, `INSERT OR REPLACE INTO daily_quotes (code, date, adjustment_factor, data) VALUES (?, ?, ?, ?)`,
+				q.Code, q.Date, q.AdjustmentFactor, string(data))
+			if err != nil {
+				return err
+			}
+		}
+		return markSyncedTx(ctx, tx, DatasetDailyQuotes, date, markSynced)
+	})
+}
+
+// date に開示された決算を保存する
+func (s *Store) SaveStatements(ctx context.Context, date string, statements []jquants.FinancialStatement, markSynced bool) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, st := range statements {
+			data, err := json.Marshal(st)
+			if err != nil {
+				return err
+			}
+			_, err = tx.ExecContext(ctx, `INSERT OR REPLACE INTO statements (disclosure_number, disclosed_date, local_code, data) VALUES (?, ?, ?, ?)`,
+				st.DisclosureNumber, st.DisclosedDate, st.LocalCode, string(data))
+			if err != nil {
+				return err
+			}
+		}
+		return markSyncedTx(ctx, tx, DatasetStatements, date, markSynced)
+	})
+}
+
+// date 時点の上場銘柄一覧を保存する
+// 前回から変わった銘柄と一覧から消えた銘柄だけ行を追加する
+func (s *Store) SaveListedInfo(ctx context.Context, date string, infos []jquants.ListedInfo) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		prev, err := listedInfoAsOf(ctx, tx, date, "", true)
+		if err != nil {
+			return err
+		}
+
+		seen := make(map[string]bool, len(infos))
+		for _, info := range infos {
+			seen[info.Code] = true
+			info.Date = "" // 適用日は行の date 列で持つ (中身の比較から外す)
+			data, err := json.Marshal(info)
+			if err != nil {
+				return err
+			}
+			if p, ok := prev[info.Code]; ok && p.Valid && p.String == string(data) {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, `INSERT OR REPLACE INTO listed_info (code, date, data) VALUES (?, ?, ?)`, info.Code, date, string(data)); err != nil {
+				return err
+			}
+		}
+		for code, p := range prev {
+			if p.Valid && !seen[code] {
+				if _, err := tx.ExecContext(ctx, `INSERT OR REPLACE INTO listed_info (code, date, data) VALUES (?, ?, NULL)`, code, date); err != nil {
+					return err
+				}
+			}
+		}
+		return markSyncedTx(ctx, tx, DatasetListedInfo, date, true)
+	})
+}
+
+func markSyncedTx(ctx context.Context, tx *sql.Tx, dataset string, date string, mark bool) error {
+	if !mark {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO synced (dataset, date) VALUES (?, ?)`, dataset, date)
+	return err
+}
+
+func (s *Store) inTx(ctx context.Context, fn func(*sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// from〜to (両端を含む) の日付を "YYYY-MM-DD" で返す
+func datesBetween(from, to string) ([]string, error) {
+	f, err := time.Parse(dateLayout, from)
+	if err != nil {
+		return nil, err
+	}
+	t, err := time.Parse(dateLayout, to)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for d := f; !d.After(t); d = d.AddDate(0, 0, 1) {
+		out = append(out, d.Format(dateLayout))
+	}
+	return out, nil
+}
+
+// J-Quants と同じく "YYYYMMDD" も受け付ける
+func normalizeDate(s string) string {
+	if t, err := time.Parse("20060102", s); err == nil {
+		return t.Format(dateLayout)
+	}
+	return s
+}
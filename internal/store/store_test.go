@@ -0,0 +1,210 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"math"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+)
+
+// 2025-07-14 (月)〜07-22 (火)。19・20日は土日、21日は祝日
+var testCalendar = []jquants.TradingCalendarDay{
+	{Date: "2025-07-14", HolidayDivision: jquants.HolidayBusinessDay},
+	{Date: "2025-07-15", HolidayDivision: jquants.HolidayBusinessDay},
+	{Date: "2025-07-16", HolidayDivision: jquants.HolidayBusinessDay},
+	{Date: "2025-07-17", HolidayDivision: jquants.HolidayBusinessDay},
+	{Date: "2025-07-18", HolidayDivision: jquants.HolidayBusinessDay},
+	{Date: "2025-07-19", HolidayDivision: jquants.HolidayNonBusinessDay},
+	{Date: "2025-07-20", HolidayDivision: jquants.HolidayNonBusinessDay},
+	{Date: "2025-07-21", HolidayDivision: jquants.HolidayNonBusinessDay},
+	{Date: "2025-07-22", HolidayDivision: jquants.HolidayBusinessDay},
+}
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(filepath.Join(t.TempDir(), "jquants.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	if err := s.SaveTradingCalendar(context.Background(), testCalendar); err != nil {
+		t.Fatalf("SaveTradingCalendar: %v", err)
+	}
+	return s
+}
+
+// 1銘柄の日足を日付ごとに保存する。skip の日は同期しない
+func saveQuotes(t *testing.T, s *Store, quotes []jquants.DailyQuote, skip ...string) {
+	t.Helper()
+	for _, q := range quotes {
+		if slices.Contains(skip, q.Date) {
+			continue
+		}
+		if err := s.SaveDailyQuotes(context.Background(), q.Date, []jquants.DailyQuote{q}, true); err != nil {
+			t.Fatalf("SaveDailyQuotes %s: %v", q.Date, err)
+		}
+	}
+}
+
+// 07-14〜07-18 と 07-22 の終値 1000 (07-17 に 1:2 の分割があれば 07-17 以降は 500)
+func testQuotes(splitOn string) []jquants.DailyQuote {
+	var quotes []jquants.DailyQuote
+	price := 1000.0
+	for _, d := range testCalendar {
+		if !d.IsTradingDay() {
+			continue
+		}
+		factor := 1.0
+		if d.Date == splitOn {
+			factor = 0.5
+			price /= 2
+		}
+		quotes = append(quotes, jquants.DailyQuote{Date: d.Date, Code: "72030", Open: price, High: price, Low: price, Close: price, Volume: 100, AdjustmentFactor: factor})
+	}
+	return quotes
+}
+
+func TestGetDailyQuotes(t *testing.T) {
+	tests := []struct {
+		name      string
+		splitOn   string
+		skip      []string // 同期しない日
+		from, to  string
+		wantErr   error
+		wantClose []float64 // 調整済み終値
+	}{
+		{"no split", "", nil, "2025-07-14", "2025-07-16", nil, []float64{1000, 1000, 1000}},
+		// 期間より後の分割も最新日基準の調整済み価格に反映する
+		{"split after to", "2025-07-17", nil, "2025-07-14", "2025-07-16", nil, []float64{500, 500, 500}},
+		{"split inside range", "2025-07-17", nil, "2025-07-16", "2025-07-18", nil, []float64{500, 500, 500}},
+		// 土日・祝日は同期しなくてよい
+		{"across holidays", "", nil, "2025-07-18", "2025-07-22", nil, []float64{1000, 1000}},
+		{"YYYYMMDD", "", nil, "20250714", "20250715", nil, []float64{1000, 1000}},
+		{"gap in synced dates", "", []string{"2025-07-16"}, "2025-07-14", "2025-07-18", ErrNotSynced, nil},
+		{"outside calendar", "", nil, "2025-07-22", "2025-07-23", ErrNotSynced, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := openTestStore(t)
+			saveQuotes(t, s, testQuotes(tt.splitOn), tt.skip...)
+
+			quotes, err := s.GetDailyQuotesContext(context.Background(), "7203", tt.from, tt.to)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if len(quotes) != len(tt.wantClose) {
+				t.Fatalf("got %d quotes, want %d", len(quotes), len(tt.wantClose))
+			}
+			for i, q := range quotes {
+				if math.Abs(q.AdjustmentClose-tt.wantClose[i]) > 1e-9 {
+					t.Errorf("%s: adjusted close = %v, want %v", q.Date, q.AdjustmentClose, tt.wantClose[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGetDailyQuotesByDate(t *testing.T) {
+	tests := []struct {
+		name      string
+		splitOn   string
+		date      string
+		wantClose float64
+		wantErr   error
+	}{
+		{"no split", "", "2025-07-15", 1000, nil},
+		{"split after date", "2025-07-17", "2025-07-15", 500, nil},
+		{"split on date", "2025-07-17", "2025-07-17", 500, nil},
+		{"not synced", "", "2025-07-23", 0, ErrNotSynced},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := openTestStore(t)
+			saveQuotes(t, s, testQuotes(tt.splitOn))
+
+			quotes, err := s.GetDailyQuotesByDate(context.Background(), tt.date)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if len(quotes) != 1 || math.Abs(quotes[0].AdjustmentClose-tt.wantClose) > 1e-9 {
+				t.Errorf("got %+v, want one quote with adjusted close %v", quotes, tt.wantClose)
+			}
+		})
+	}
+}
+
+func TestGetListedInfo(t *testing.T) {
+	ctx := context.Background()
+	s := openTestStore(t)
+	toyota := jquants.ListedInfo{Code: "72030", CompanyName: "トヨタ自動車", MarketCode: jquants.MarketPrime}
+	sony := jquants.ListedInfo{Code: "67580", CompanyName: "ソニーグループ", MarketCode: jquants.MarketPrime}
+	renamed := sony
+	renamed.CompanyName = "ソニー"
+
+	// 07-14: 2銘柄 / 07-15: 変化なし / 07-16: 67580 が社名変更 / 07-17: 72030 が上場廃止 / 07-18: 同期しない
+	days := []struct {
+		date  string
+		infos []jquants.ListedInfo
+	}{
+		{"2025-07-14", []jquants.ListedInfo{toyota, sony}},
+		{"2025-07-15", []jquants.ListedInfo{toyota, sony}},
+		{"2025-07-16", []jquants.ListedInfo{toyota, renamed}},
+		{"2025-07-17", []jquants.ListedInfo{renamed}},
+	}
+	for _, d := range days {
+		if err := s.SaveListedInfo(ctx, d.date, d.infos); err != nil {
+			t.Fatalf("SaveListedInfo %s: %v", d.date, err)
+		}
+	}
+	var rows int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM listed_info`).Scan(&rows); err != nil {
+		t.Fatal(err)
+	}
+	// 初日の2行 + 社名変更 + 上場廃止 (変化のない日は行を足さない)
+	if rows != 4 {
+		t.Errorf("listed_info has %d rows, want 4", rows)
+	}
+
+	tests := []struct {
+		name      string
+		code      string
+		date      string
+		wantNames []string // Code 順
+		wantErr   error
+	}{
+		{"first day", "", "2025-07-14", []string{"ソニーグループ", "トヨタ自動車"}, nil},
+		{"unchanged day", "", "2025-07-15", []string{"ソニーグループ", "トヨタ自動車"}, nil},
+		{"renamed", "67580", "2025-07-16", []string{"ソニー"}, nil},
+		{"before delisting", "7203", "2025-07-16", []string{"トヨタ自動車"}, nil},
+		{"after delisting", "", "2025-07-17", []string{"ソニー"}, nil},
+		{"delisted code", "72030", "2025-07-17", []string{}, nil},
+		// 空なら最終同期日
+		{"latest", "", "", []string{"ソニー"}, nil},
+		// 07-18 は立会日なのに同期していない
+		{"not synced", "", "2025-07-18", nil, ErrNotSynced},
+		{"before first sync", "", "2025-07-13", nil, ErrNotSynced},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			infos, err := s.GetListedInfo(ctx, tt.code, tt.date)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if len(infos) != len(tt.wantNames) {
+				t.Fatalf("got %+v, want %v", infos, tt.wantNames)
+			}
+			for i, info := range infos {
+				if info.CompanyName != tt.wantNames[i] {
+					t.Errorf("[%d] = %s, want %s", i, info.CompanyName, tt.wantNames[i])
+				}
+			}
+		})
+	}
+}
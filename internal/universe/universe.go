@@ -0,0 +1,113 @@
+// 分析対象の銘柄の絞り込み (市場区分・業種)
+package universe
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/oooooorriiiii/stock-agent-jpx/internal/jquants"
+)
+
+// 銘柄の絞り込み条件。ゼロ値なら全銘柄が対象
+type Filter struct {
+	Markets         []string // 対象の市場区分コード (空なら全市場)
+	ExcludeSector33 []string // 除外する33業種コード
+	ExcludeSector17 []string // 除外する17業種コード
+}
+
+// -market で使える市場区分の別名
+var marketAliases = map[string]string{
+	"prime":    jquants.MarketPrime,
+	"standard": jquants.MarketStandard,
+	"growth":   jquants.MarketGrowth,
+}
+
+// -exclude-sectors で使える業種の別名 (33業種コード)
+var sectorAliases = map[string][]string{
+	"banks":      {"7050"},
+	"financials": {"7050", "7100", "7150", "7200"}, // 銀行・証券・保険・その他金融
+	"realestate": {"8050"},
+}
+
+// "prime,standard" のようなカンマ区切りの市場区分を Filter.Markets に変換する
+// 別名のほか "0111" のような市場区分コードも直接指定できる
+func ParseMarkets(s string) ([]string, error) {
+	var codes []string
+	for _, m := range splitList(s) {
+		if code, ok := marketAliases[strings.ToLower(m)]; ok {
+			codes = append(codes, code)
+			continue
+		}
+		if len(m) != 4 || !isDigits(m) {
+			return nil, fmt.Errorf("unknown market %q (want prime, standard, growth or a 4-digit market code)", m)
+		}
+		codes = append(codes, m)
+	}
+	return codes, nil
+}
+
+// "banks,9050,15" のようなカンマ区切りの業種を33業種・17業種コードに振り分ける
+// 4桁は33業種コード、1〜2桁は17業種コードとして扱う
+func ParseSectors(s string) (sector33, sector17 []string, err error) {
+	for _, v := range splitList(s) {
+		if codes, ok := sectorAliases[strings.ToLower(v)]; ok {
+			sector33 = append(sector33, codes...)
+			continue
+		}
+		switch {
+		case isDigits(v) && len(v) == 4:
+			sector33 = append(sector33, v)
+		case isDigits(v) && len(v) <= 2:
+			sector17 = append(sector17, v)
+		default:
+			return nil, nil, fmt.Errorf("unknown sector %q (want banks, financials, realestate, a 33-sector code like 7050 or a 17-sector code like 15)", v)
+		}
+	}
+	return sector33, sector17, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func isDigits(s string) bool {
+	return strings.Trim(s, "0123456789") == ""
+}
+
+// 条件が何も指定されていないか
+func (f Filter) IsZero() bool {
+	return len(f.Markets) == 0 && len(f.ExcludeSector33) == 0 && len(f.ExcludeSector17) == 0
+}
+
+// info が対象銘柄か
+func (f Filter) Match(info jquants.ListedInfo) bool {
+	if len(f.Markets) > 0 && !slices.Contains(f.Markets, info.MarketCode) {
+		return false
+	}
+	return !slices.Contains(f.ExcludeSector33, info.Sector33Code) && !slices.Contains(f.ExcludeSector17, info.Sector17Code)
+}
+
+// ログ向けの表現
+func (f Filter) String() string {
+	if f.IsZero() {
+		return "all listed companies"
+	}
+	var parts []string
+	if len(f.Markets) > 0 {
+		parts = append(parts, "markets="+strings.Join(f.Markets, ","))
+	}
+	if len(f.ExcludeSector33) > 0 {
+		parts = append(parts, "exclude sector33="+strings.Join(f.ExcludeSector33, ","))
+	}
+	if len(f.ExcludeSector17) > 0 {
+		parts = append(parts, "exclude sector17="+strings.Join(f.ExcludeSector17, ","))
+	}
+	return strings.Join(parts, " ")
+}